@@ -0,0 +1,28 @@
+package sqlite3dump
+
+import "strings"
+
+// attachment is a database the dump attaches, see WithAttach.
+type attachment struct {
+	name string
+	path string
+}
+
+// attachStatements returns the ATTACH DATABASE statements of WithAttach, written before
+// the transaction of the dump since SQLite can't attach a database within one.
+func (s3d *Dumper) attachStatements() []string {
+	statements := make([]string, len(s3d.attachments))
+	for i, a := range s3d.attachments {
+		statements[i] = "ATTACH DATABASE " + QuoteValue(a.path) + " AS " + s3d.dumpIdent(a.name) + ";\n"
+	}
+	return statements
+}
+
+// targetSchema returns the schema the statements writing to the table target,
+// that of WithTableTargetSchema or WithRenameSchema, or "" for an unqualified name.
+func (s3d *Dumper) targetSchema(table string) string {
+	if schema, ok := s3d.tableTargetSchemas[strings.ToLower(table)]; ok {
+		return schema
+	}
+	return s3d.renameSchema
+}
@@ -0,0 +1,50 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupTo(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, x TEXT)`,
+		`CREATE TABLE u(y BLOB)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100) INSERT INTO t SELECT i, 'row ' || i FROM n`,
+		`INSERT INTO u VALUES(x'00ff'), (NULL)`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the quote in the name is escaped
+	destPath := filepath.Join(t.TempDir(), "it's.db")
+	require.NoError(t, BackupTo(db, destPath, false))
+
+	backup, err := sql.Open("sqlite3", destPath)
+	require.NoError(t, err)
+	defer backup.Close()
+	for _, table := range []string{"t", "u"} {
+		var expect, got int
+		require.NoError(t, db.QueryRow(`SELECT count(*) FROM "`+table+`"`).Scan(&expect))
+		require.NoError(t, backup.QueryRow(`SELECT count(*) FROM "`+table+`"`).Scan(&got))
+		assert.Equal(t, expect, got, table)
+	}
+	backup.Close()
+
+	err = BackupTo(db, destPath, false)
+	assert.EqualError(t, err, `backup destination "`+destPath+`" already exists`)
+
+	_, err = db.Exec(`DELETE FROM t WHERE id > 10`)
+	require.NoError(t, err)
+	require.NoError(t, BackupTo(db, destPath, true))
+	backup, err = sql.Open("sqlite3", destPath)
+	require.NoError(t, err)
+	defer backup.Close()
+	var count int
+	require.NoError(t, backup.QueryRow(`SELECT count(*) FROM t`).Scan(&count))
+	assert.Equal(t, 10, count)
+}
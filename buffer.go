@@ -0,0 +1,31 @@
+package sqlite3dump
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+)
+
+// buffer returns the outputs of the dump buffered with WithBufferSize, and the function
+// writing out what's left in the buffers. A writer used for both gets a single buffer.
+func (s3d *Dumper) buffer(schemaOut, dataOut io.Writer) (bufferedSchemaOut, bufferedDataOut io.Writer, flush func() error) {
+	data := bufio.NewWriterSize(dataOut, s3d.bufferSize)
+	if sameWriter(schemaOut, dataOut) {
+		return data, data, data.Flush
+	}
+
+	schema := bufio.NewWriterSize(schemaOut, s3d.bufferSize)
+	return schema, data, func() error {
+		err := schema.Flush()
+		if dataErr := data.Flush(); err == nil {
+			err = dataErr
+		}
+		return err
+	}
+}
+
+// sameWriter reports whether a and b are the same writer, comparing them only if their type allows it.
+func sameWriter(a, b io.Writer) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
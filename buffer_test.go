@@ -0,0 +1,73 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWriter counts the writes to it.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestWithBufferSize(t *testing.T) {
+	expect, err := DumpString("testdata/cars.db")
+	require.NoError(t, err)
+
+	var unbuffered, buffered countingWriter
+	require.NoError(t, Dump("testdata/cars.db", &unbuffered))
+	require.NoError(t, Dump("testdata/cars.db", &buffered, WithBufferSize(1<<16)))
+	assert.Equal(t, expect, buffered.String())
+	assert.Equal(t, 11, unbuffered.writes)
+	assert.Equal(t, 1, buffered.writes)
+
+	// the schema and the data get a buffer each
+	var schema, data countingWriter
+	require.NoError(t, DumpSplit("testdata/cars.db", &schema, &data, WithBufferSize(1<<16)))
+	assert.Equal(t, "CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n", schema.String())
+	assert.Contains(t, data.String(), `INSERT INTO "Cars" VALUES(8,'Volkswagen',21600);`)
+	assert.Equal(t, 1, schema.writes)
+	assert.Equal(t, 1, data.writes)
+
+	// the write error of the final flush is returned
+	err = Dump("testdata/cars.db", &limitWriter{n: 10}, WithBufferSize(1<<16))
+	assert.True(t, errors.Is(err, errWriterFull), "%v", err)
+	err = Dump("testdata/cars.db", &limitWriter{n: 100}, WithBufferSize(64))
+	assert.True(t, errors.Is(err, errWriterFull), "%v", err)
+}
+
+func BenchmarkDump(b *testing.B) {
+	dbName := createDB(b,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, name TEXT, price REAL)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10000)
+		INSERT INTO t SELECT i, printf('name %d', i), i / 3.0 FROM n`,
+	)
+
+	for name, options := range map[string][]Option{
+		"unbuffered": nil,
+		"buffered":   {WithBufferSize(64 << 10)},
+	} {
+		b.Run(name, func(b *testing.B) {
+			// a file, as the writes of the dump are syscalls unless buffered
+			out, err := os.Create(filepath.Join(b.TempDir(), "dump.sql"))
+			require.NoError(b, err)
+			defer out.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, Dump(dbName, out, options...))
+			}
+		})
+	}
+}
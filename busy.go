@@ -0,0 +1,32 @@
+package sqlite3dump
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// retryBusy reports whether the query that failed with err at the attempt should run again,
+// as the database was locked and WithBusyRetry allows another attempt. It waits for the
+// delay before returning true.
+func (s3d *Dumper) retryBusy(ctx context.Context, err error, attempt int) bool {
+	if err == nil || attempt >= s3d.busyAttempts || !isBusy(err) {
+		return false
+	}
+	timer := time.NewTimer(s3d.busyDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isBusy reports whether err is the SQLITE_BUSY or SQLITE_LOCKED error of the driver.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
@@ -0,0 +1,50 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBusyRetry(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+	)
+
+	// an exclusive lock keeps the dump from reading in the default rollback journal mode
+	writer, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer writer.Close()
+	conn, err := writer.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), `BEGIN EXCLUSIVE`)
+	require.NoError(t, err)
+
+	// without the busy timeout of the driver, so a locked database fails at once
+	db, err := sql.Open("sqlite3", dbName+"?_busy_timeout=0")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = DumpDBString(db)
+	require.Error(t, err)
+	assert.True(t, isBusy(err), "%v", err)
+	_, err = DumpDBString(db, WithBusyRetry(3, time.Millisecond))
+	assert.True(t, isBusy(err), "%v", err)
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, err := conn.ExecContext(context.Background(), `COMMIT`)
+		released <- err
+	}()
+	got, err := DumpDBString(db, WithBusyRetry(100, 10*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, <-released)
+	assert.Contains(t, got, `INSERT INTO "t" VALUES(1);`)
+}
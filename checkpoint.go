@@ -0,0 +1,87 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"io"
+)
+
+// checkpointWriter writes the statements inserting rows, one per Write, committing the
+// transaction of the dump and beginning a new one between every n statements.
+// The checkpoint is only written before the statement that follows the n-th, so the
+// dump never ends with an empty transaction.
+type checkpointWriter struct {
+	w          io.Writer
+	every      int
+	begin      string
+	commit     string
+	statements int
+}
+
+func (cw *checkpointWriter) Write(p []byte) (int, error) {
+	if cw.statements == cw.every {
+		if err := writeStatement(cw.w, cw.commit); err != nil {
+			return 0, err
+		}
+		if err := writeStatement(cw.w, cw.begin); err != nil {
+			return 0, err
+		}
+		cw.statements = 0
+	}
+	cw.statements++
+	return cw.w.Write(p)
+}
+
+// statementBuffer buffers the statements written to it, one per Write,
+// so they can be copied out one by one.
+type statementBuffer struct {
+	bytes.Buffer
+	ends []int
+}
+
+func (b *statementBuffer) Write(p []byte) (int, error) {
+	n, err := b.Buffer.Write(p)
+	b.ends = append(b.ends, b.Len())
+	return n, err
+}
+
+// writeStatementsTo writes the buffered statements to w, one per Write.
+func (b *statementBuffer) writeStatementsTo(w io.Writer) error {
+	buf, start := b.Bytes(), 0
+	for _, end := range b.ends {
+		if _, err := w.Write(buf[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// dumpStart writes the statements starting the dump, the foreign_keys pragma and the BEGIN
+// of the transaction, right before the first statement written through one of its writers.
+type dumpStart struct {
+	w          io.Writer
+	statements []string
+	started    bool
+}
+
+// writer returns a writer that starts the dump before writing to w.
+func (ds *dumpStart) writer(w io.Writer) io.Writer {
+	return startingWriter{start: ds, w: w}
+}
+
+type startingWriter struct {
+	start *dumpStart
+	w     io.Writer
+}
+
+func (sw startingWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 && !sw.start.started {
+		sw.start.started = true
+		for _, statement := range sw.start.statements {
+			if err := writeStatement(sw.start.w, statement); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return sw.w.Write(p)
+}
@@ -0,0 +1,74 @@
+package sqlite3dump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCheckpointEvery(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`CREATE TABLE b(n INTEGER)`,
+		`INSERT INTO a VALUES(1), (2), (3)`,
+		`INSERT INTO b VALUES(4)`,
+	)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE a(n INTEGER);\n" +
+		`INSERT INTO "a" VALUES(1);` + "\n" +
+		`INSERT INTO "a" VALUES(2);` + "\n" +
+		"COMMIT;\n" +
+		"BEGIN TRANSACTION;\n" +
+		`INSERT INTO "a" VALUES(3);` + "\n" +
+		"CREATE TABLE b(n INTEGER);\n" +
+		`INSERT INTO "b" VALUES(4);` + "\n" +
+		"COMMIT;\n"
+
+	got, err := DumpString(dbName, WithCheckpointEvery(2))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+
+	// the count runs across the tables dumped by the workers too
+	got, err = DumpString(dbName, WithCheckpointEvery(2), WithConcurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+
+	restoreDB(t, got)
+}
+
+func TestWithCheckpointEveryNoEmptyTransaction(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`INSERT INTO a VALUES(1), (2), (3), (4)`,
+	)
+
+	got, err := DumpString(dbName, WithCheckpointEvery(2), WithTransactionMode("immediate"))
+	require.NoError(t, err)
+
+	expect := "BEGIN IMMEDIATE TRANSACTION;\n" +
+		"CREATE TABLE a(n INTEGER);\n" +
+		`INSERT INTO "a" VALUES(1);` + "\n" +
+		`INSERT INTO "a" VALUES(2);` + "\n" +
+		"COMMIT;\n" +
+		"BEGIN IMMEDIATE TRANSACTION;\n" +
+		`INSERT INTO "a" VALUES(3);` + "\n" +
+		`INSERT INTO "a" VALUES(4);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithCheckpointEveryWithoutTransaction(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`INSERT INTO a VALUES(1), (2), (3)`,
+	)
+
+	got, err := DumpString(dbName, WithCheckpointEvery(1), WithTransaction(false))
+	require.NoError(t, err)
+	assert.NotContains(t, got, "COMMIT")
+	assert.NotContains(t, got, "BEGIN")
+	assert.Equal(t, 3, strings.Count(got, "INSERT INTO"))
+}
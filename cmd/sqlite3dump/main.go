@@ -1,30 +1,131 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/thlib/sqlite3dump"
 )
 
+const usage = "usage: sqlite3dump [-gzip] [-migration] [-drop] [-no-transaction] [-o database.sql] database.db > database.sql\n"
+
 func main() {
+	gzipped := flag.Bool("gzip", false, "gzip compress the dump")
+	migration := flag.Bool("migration", false, "dump only the rows, as INSERT statements with column names")
+	drop := flag.Bool("drop", false, "drop the tables and indexes if they exist before creating them")
+	noTransaction := flag.Bool("no-transaction", false, "don't wrap the dump in a transaction")
+	output := flag.String("o", "", "write the dump to the file instead of stdout")
+	flag.Parse()
+
+	// the dump is buffered by the library rather than by a bufio.Writer around the output
+	opts := []sqlite3dump.Option{sqlite3dump.WithBufferSize(64 << 10)}
+	if *migration {
+		opts = append(opts, sqlite3dump.WithMigration())
+	}
+	if *drop {
+		opts = append(opts, sqlite3dump.WithDropIfExists(true))
+	}
+	if *noTransaction {
+		opts = append(opts, sqlite3dump.WithTransaction(false))
+	}
+
+	failed := 0
 	err := func() (err error) {
-		if len(os.Args) < 2 {
+		if flag.NArg() < 1 {
 			err = fmt.Errorf("incorrect usage")
 			return
 		}
-		f := bufio.NewWriter(os.Stdout)
-		err = sqlite3dump.Dump(os.Args[1], f)
-		f.Flush()
+		out := os.Stdout
+		if *output != "" {
+			out, err = os.Create(*output)
+			if err != nil {
+				return
+			}
+			defer func() {
+				if closeErr := out.Close(); err == nil {
+					err = closeErr
+				}
+				// don't leave a partial dump behind
+				if err != nil || failed == flag.NArg() {
+					os.Remove(*output)
+				}
+			}()
+		}
+		if flag.NArg() == 1 {
+			return dump(out, flag.Arg(0), *gzipped, opts)
+		}
+
+		// the databases that fail are reported and skipped, each is dumped into a temporary
+		// file first so that one failing leaves none of its statements in the output, and
+		// the gzip streams of the databases are concatenated, which gunzip reads as one
+		for _, dbName := range flag.Args() {
+			dbOpts := append([]sqlite3dump.Option{sqlite3dump.WithHeaderText("file: " + dbName)}, opts...)
+			tmp, dumpErr := dumpToTemp(dbName, *gzipped, dbOpts)
+			if dumpErr != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", dbName, dumpErr)
+				failed++
+				continue
+			}
+			err = copyDump(out, tmp)
+			if err != nil {
+				return
+			}
+		}
 		return
 	}()
+	// a single database exits as it always did, with status 0
 	if err != nil {
 		fmt.Fprintf(os.Stderr, err.Error()+"\n")
-		fmt.Fprintf(os.Stderr, "usage: sqlite3dump database.db > database.sql\n")
+		fmt.Fprintf(os.Stderr, usage)
+		if flag.NArg() > 1 {
+			os.Exit(1)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// dumpToTemp dumps the database into a temporary file, which the caller removes.
+func dumpToTemp(dbName string, gzipped bool, opts []sqlite3dump.Option) (tmp *os.File, err error) {
+	tmp, err = ioutil.TempFile("", "sqlite3dump-*.sql")
+	if err != nil {
+		return
+	}
+	if err = dump(tmp, dbName, gzipped, opts); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return
+}
+
+// copyDump writes the dump of the temporary file into w and removes the file.
+func copyDump(w io.Writer, tmp *os.File) (err error) {
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	_, err = io.Copy(w, tmp)
+	return
+}
+
+// dump dumps the database into w, gzip compressed if gzipped, and reports it on stderr.
+func dump(w io.Writer, dbName string, gzipped bool, opts []sqlite3dump.Option) (err error) {
+	if gzipped {
+		err = sqlite3dump.DumpGzip(dbName, w, opts...)
 	} else {
-		_, fname := filepath.Split(os.Args[1])
-		fmt.Fprintf(os.Stderr, "dumped %s\n", fname)
+		err = sqlite3dump.Dump(dbName, w, opts...)
+	}
+	if err != nil {
+		return err
 	}
+	_, fname := filepath.Split(dbName)
+	fmt.Fprintf(os.Stderr, "dumped %s\n", fname)
+	return nil
 }
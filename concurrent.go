@@ -0,0 +1,82 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// tableRows holds the rows of a table dumped by a worker.
+type tableRows struct {
+	buf        statementBuffer
+	rowsDumped int64
+	err        error
+	done       chan struct{}
+}
+
+// dumpRowsConcurrently starts dumping the rows of the tables on s3d.concurrency workers.
+// The returned writeRows waits for the rows of a table and copies them to out, it must be
+// called for the tables in order. wait cancels the workers still running and waits for them.
+// The first error of a worker cancels the others.
+func (s3d *Dumper) dumpRowsConcurrently(ctx context.Context, db preparer, tables []schema, out io.Writer) (writeRows func(schema) (int64, error), wait func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// the first error is returned for every table not dumped, rather than the cancellation it causes
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	results := make(map[tableKey]*tableRows, len(tables))
+	jobs := make(chan schema, len(tables))
+	for _, schema := range tables {
+		results[keyOf(schema)] = &tableRows{done: make(chan struct{})}
+		jobs <- schema
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s3d.concurrency && i < len(tables); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for schema := range jobs {
+				result := results[keyOf(schema)]
+				if err := ctx.Err(); err != nil {
+					result.err = err
+				} else {
+					result.rowsDumped, result.err = s3d.writeInsStmtsForTableRows(ctx, &result.buf, db, schema, nil)
+				}
+				if result.err != nil {
+					fail(result.err)
+				}
+				close(result.done)
+			}
+		}()
+	}
+
+	writeRows = func(schema schema) (int64, error) {
+		result := results[keyOf(schema)]
+		<-result.done
+		if result.err != nil {
+			return result.rowsDumped, firstErr
+		}
+		// the statements are copied one by one for the checkpoints of WithCheckpointEvery
+		if err := result.buf.writeStatementsTo(out); err != nil {
+			return result.rowsDumped, fmt.Errorf("failed to write the rows of table %q: %w", schema.Name, err)
+		}
+		return result.rowsDumped, nil
+	}
+	wait = func() {
+		cancel()
+		wg.Wait()
+	}
+	return
+}
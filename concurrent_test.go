@@ -0,0 +1,48 @@
+package sqlite3dump
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createConcurrencyDB(t *testing.T) string {
+	statements := []string{}
+	for i := 0; i < 20; i++ {
+		statements = append(statements,
+			fmt.Sprintf(`CREATE TABLE t%02d(n INTEGER, s TEXT)`, i),
+			fmt.Sprintf(`WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < %d)
+				INSERT INTO t%02d SELECT n, 'row ' || n FROM seq`, 50+i*10, i),
+		)
+	}
+	statements = append(statements, `CREATE INDEX t00_n ON t00(n)`)
+	return createDB(t, statements...)
+}
+
+func TestWithConcurrency(t *testing.T) {
+	dbName := createConcurrencyDB(t)
+
+	expect, err := DumpString(dbName, WithStableOrder())
+	require.NoError(t, err)
+
+	for _, n := range []int{2, 4, 50} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			got, err := DumpString(dbName, WithStableOrder(), WithConcurrency(n))
+			require.NoError(t, err)
+			assert.Equal(t, expect, got)
+		})
+	}
+}
+
+func TestWithConcurrencyError(t *testing.T) {
+	dbName := createConcurrencyDB(t)
+
+	got, err := DumpString(dbName, WithConcurrency(4), WithWhere("t10", "no_such_column = 1"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no_such_column")
+	assert.NotContains(t, got, `INSERT INTO "t10"`)
+	assert.False(t, strings.HasSuffix(got, "COMMIT;\n"))
+}
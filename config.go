@@ -0,0 +1,285 @@
+package sqlite3dump
+
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// Config holds the options of a dump as a struct, such as one read from a configuration
+// file with encoding/json, which matches the field names case-insensitively.
+// Its zero value dumps like Dump without options, and every field is the option of the same
+// name, see Options. The fields holding functions can't be read from a file, they're left
+// out of the JSON encoding.
+type Config struct {
+	Migration    bool
+	DropIfExists bool
+	// WrapWithTransaction is WithTransaction, the dump is wrapped when it's nil
+	WrapWithTransaction *bool
+	TransactionMode     string
+	// BeginStatement and CommitStatement are WithTransactionStatements, when either is set
+	BeginStatement  string
+	CommitStatement string
+	// WithoutData is WithoutData, dumping the schema only
+	WithoutData       bool
+	DataOnly          bool
+	Tables            []string
+	ExcludeTables     []string
+	TableGlobs        []string
+	ExcludeTableGlobs []string
+	InsertBatchSize   int
+	Where             map[string]string
+	StableOrder       bool
+	ForeignKeysOff    bool
+	DependencyOrder   bool
+	WithoutSnapshot   bool
+	ColumnNames       bool
+	Schema            string
+	IfNotExists       bool
+	Concurrency       int
+	HeaderComment     bool
+	HeaderText        string
+	Sequences         bool
+	CheckpointEvery   int
+	// IdentifierQuote is a single character, WithIdentifierQuote
+	IdentifierQuote string
+	NullAs          *string
+	MaxRowsPerTable int
+	TableLimits     map[string]int
+	UTF8BOM         bool
+	WithoutIndexes  bool
+	WithoutTriggers bool
+	WithoutViews    bool
+	ReadOnly        bool
+	// TimeColumns maps the tables to their columns of WithTimeColumn
+	TimeColumns           map[string][]string
+	InsertVerb            string
+	Temp                  bool
+	RenameSchema          string
+	ValidateOnly          bool
+	KeysetPagination      map[string]string
+	LineEnding            string
+	BufferSize            int
+	TableRowCountComments bool
+	OnlyTypes             []string
+	// PragmaState writes the PragmaStateNames, or the default ones, see WithPragmaState
+	PragmaState      bool
+	PragmaStateNames []string
+	// BusyRetryAttempts and BusyRetryDelay are WithBusyRetry, when attempts are set
+	BusyRetryAttempts int
+	BusyRetryDelay    time.Duration
+	Footers           []string
+	SkipEmptyTables   bool
+	SkipDanglingViews bool
+	MaxBytes          int64
+	OrderBy           map[string]string
+	// TableTargetSchemas maps the tables to the schemas of WithTableTargetSchema
+	TableTargetSchemas map[string]string
+	// Attach maps the schema names to the paths of WithAttach, attached in the order of the names
+	Attach map[string]string
+	// ResumeTable and ResumeAfterRowid are WithResumeFrom, when the table is set
+	ResumeTable           string
+	ResumeAfterRowid      int64
+	QueryOnly             bool
+	SchemaQuery           string
+	PerTableSavepoints    bool
+	ExplicitSequenceTable bool
+
+	Progress         func(table string, rowsDumped int64)                                     `json:"-"`
+	Warn             func(msg string)                                                         `json:"-"`
+	RowFilters       map[string]func(cols []string, vals []interface{}) (bool, []interface{}) `json:"-"`
+	ColumnTransforms map[string]map[string]func(v interface{}) interface{}                    `json:"-"`
+	BeforeTable      func(table string) string                                                `json:"-"`
+	AfterTable       func(table string) string                                                `json:"-"`
+	CreateRewriter   func(objType, name, sql string) string                                   `json:"-"`
+	Dialect          Dialect                                                                  `json:"-"`
+}
+
+// DumpWithConfig dumps the database like Dump, with the options of the Config.
+func DumpWithConfig(dbName string, out io.Writer, cfg Config) error {
+	return Dump(dbName, out, cfg.Options()...)
+}
+
+// Options returns the options the Config stands for, leaving out those of the zero fields.
+// Invalid values, such as an unknown insert verb, are reported by dumping, like those of
+// the options.
+func (cfg Config) Options() []Option {
+	opts := []Option{}
+	flags := []struct {
+		set    bool
+		option func() Option
+	}{
+		{cfg.Migration, WithMigration},
+		{cfg.WithoutData, WithoutData},
+		{cfg.DataOnly, WithDataOnly},
+		{cfg.StableOrder, WithStableOrder},
+		{cfg.ForeignKeysOff, WithForeignKeysOff},
+		{cfg.DependencyOrder, WithDependencyOrder},
+		{cfg.WithoutSnapshot, WithoutSnapshot},
+		{cfg.ColumnNames, WithColumnNames},
+		{cfg.IfNotExists, WithIfNotExists},
+		{cfg.HeaderComment, WithHeaderComment},
+		{cfg.Sequences, WithSequences},
+		{cfg.UTF8BOM, WithUTF8BOM},
+		{cfg.WithoutIndexes, WithoutIndexes},
+		{cfg.WithoutTriggers, WithoutTriggers},
+		{cfg.WithoutViews, WithoutViews},
+		{cfg.ReadOnly, WithReadOnly},
+		{cfg.Temp, WithTemp},
+		{cfg.ValidateOnly, WithValidateOnly},
+		{cfg.TableRowCountComments, WithTableRowCountComments},
+		{cfg.SkipEmptyTables, WithSkipEmptyTables},
+		{cfg.SkipDanglingViews, WithSkipDanglingViews},
+		{cfg.QueryOnly, WithQueryOnly},
+		{cfg.PerTableSavepoints, WithPerTableSavepoints},
+		{cfg.ExplicitSequenceTable, WithExplicitSequenceTable},
+	}
+	for _, flag := range flags {
+		if flag.set {
+			opts = append(opts, flag.option())
+		}
+	}
+
+	if cfg.DropIfExists {
+		opts = append(opts, WithDropIfExists(true))
+	}
+	if cfg.WrapWithTransaction != nil {
+		opts = append(opts, WithTransaction(*cfg.WrapWithTransaction))
+	}
+	if cfg.TransactionMode != "" {
+		opts = append(opts, WithTransactionMode(cfg.TransactionMode))
+	}
+	if cfg.BeginStatement != "" || cfg.CommitStatement != "" {
+		opts = append(opts, WithTransactionStatements(cfg.BeginStatement, cfg.CommitStatement))
+	}
+	if len(cfg.Tables) > 0 {
+		opts = append(opts, WithTables(cfg.Tables...))
+	}
+	if len(cfg.ExcludeTables) > 0 {
+		opts = append(opts, WithExcludeTables(cfg.ExcludeTables...))
+	}
+	for _, pattern := range cfg.TableGlobs {
+		opts = append(opts, WithTableGlob(pattern))
+	}
+	for _, pattern := range cfg.ExcludeTableGlobs {
+		opts = append(opts, WithExcludeTableGlob(pattern))
+	}
+	if cfg.InsertBatchSize != 0 {
+		opts = append(opts, WithInsertBatchSize(cfg.InsertBatchSize))
+	}
+	for table, condition := range cfg.Where {
+		opts = append(opts, WithWhere(table, condition))
+	}
+	if cfg.Schema != "" {
+		opts = append(opts, WithSchema(cfg.Schema))
+	}
+	if cfg.Concurrency != 0 {
+		opts = append(opts, WithConcurrency(cfg.Concurrency))
+	}
+	if cfg.HeaderText != "" {
+		opts = append(opts, WithHeaderText(cfg.HeaderText))
+	}
+	if cfg.CheckpointEvery != 0 {
+		opts = append(opts, WithCheckpointEvery(cfg.CheckpointEvery))
+	}
+	if cfg.IdentifierQuote != "" {
+		// a quote of several characters is left to fail validation
+		var quote rune
+		if runes := []rune(cfg.IdentifierQuote); len(runes) == 1 {
+			quote = runes[0]
+		}
+		opts = append(opts, WithIdentifierQuote(quote))
+	}
+	if cfg.NullAs != nil {
+		opts = append(opts, WithNullAs(*cfg.NullAs))
+	}
+	if cfg.MaxRowsPerTable != 0 {
+		opts = append(opts, WithMaxRowsPerTable(cfg.MaxRowsPerTable))
+	}
+	if len(cfg.TableLimits) > 0 {
+		opts = append(opts, WithTableLimits(cfg.TableLimits))
+	}
+	for table, columns := range cfg.TimeColumns {
+		for _, column := range columns {
+			opts = append(opts, WithTimeColumn(table, column))
+		}
+	}
+	if cfg.InsertVerb != "" {
+		opts = append(opts, WithInsertVerb(cfg.InsertVerb))
+	}
+	if cfg.RenameSchema != "" {
+		opts = append(opts, WithRenameSchema(cfg.RenameSchema))
+	}
+	for table, column := range cfg.KeysetPagination {
+		opts = append(opts, WithKeysetPagination(table, column))
+	}
+	if cfg.LineEnding != "" {
+		opts = append(opts, WithLineEnding(cfg.LineEnding))
+	}
+	if cfg.BufferSize != 0 {
+		opts = append(opts, WithBufferSize(cfg.BufferSize))
+	}
+	if len(cfg.OnlyTypes) > 0 {
+		opts = append(opts, WithOnlyTypes(cfg.OnlyTypes...))
+	}
+	if cfg.PragmaState {
+		opts = append(opts, WithPragmaState(cfg.PragmaStateNames...))
+	}
+	if cfg.BusyRetryAttempts != 0 {
+		opts = append(opts, WithBusyRetry(cfg.BusyRetryAttempts, cfg.BusyRetryDelay))
+	}
+	for table, order := range cfg.OrderBy {
+		opts = append(opts, WithOrderBy(table, order))
+	}
+	for table, schema := range cfg.TableTargetSchemas {
+		opts = append(opts, WithTableTargetSchema(table, schema))
+	}
+	names := make([]string, 0, len(cfg.Attach))
+	for name := range cfg.Attach {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opts = append(opts, WithAttach(name, cfg.Attach[name]))
+	}
+	if cfg.ResumeTable != "" {
+		opts = append(opts, WithResumeFrom(cfg.ResumeTable, cfg.ResumeAfterRowid))
+	}
+	if cfg.SchemaQuery != "" {
+		opts = append(opts, WithSchemaQuery(cfg.SchemaQuery))
+	}
+	if cfg.MaxBytes != 0 {
+		opts = append(opts, WithMaxBytes(cfg.MaxBytes))
+	}
+	for _, footer := range cfg.Footers {
+		opts = append(opts, WithFooter(footer))
+	}
+
+	if cfg.Progress != nil {
+		opts = append(opts, WithProgress(cfg.Progress))
+	}
+	if cfg.Warn != nil {
+		opts = append(opts, WithWarn(cfg.Warn))
+	}
+	for table, fn := range cfg.RowFilters {
+		opts = append(opts, WithRowFilter(table, fn))
+	}
+	for table, columns := range cfg.ColumnTransforms {
+		for column, fn := range columns {
+			opts = append(opts, WithColumnTransform(table, column, fn))
+		}
+	}
+	if cfg.BeforeTable != nil {
+		opts = append(opts, WithBeforeTable(cfg.BeforeTable))
+	}
+	if cfg.AfterTable != nil {
+		opts = append(opts, WithAfterTable(cfg.AfterTable))
+	}
+	if cfg.CreateRewriter != nil {
+		opts = append(opts, WithCreateRewriter(cfg.CreateRewriter))
+	}
+	if cfg.Dialect != nil {
+		opts = append(opts, WithDialect(cfg.Dialect))
+	}
+	return opts
+}
@@ -0,0 +1,56 @@
+package sqlite3dump
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpWithConfig(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY, x)`,
+		`CREATE TABLE b(y)`,
+		`CREATE INDEX ax ON a(x)`,
+		`INSERT INTO a VALUES(1, 'one'), (2, NULL)`,
+		`INSERT INTO b VALUES(3)`,
+	)
+
+	var cfg Config
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"dropIfExists": true,
+		"wrapWithTransaction": false,
+		"tables": ["A"],
+		"columnNames": true,
+		"nullAs": "''",
+		"where": {"a": "id > 0"},
+		"footers": ["PRAGMA optimize;"]
+	}`), &cfg))
+
+	var b strings.Builder
+	require.NoError(t, DumpWithConfig(dbName, &b, cfg))
+	expect, err := DumpString(dbName,
+		WithDropIfExists(true), WithTransaction(false), WithTables("a"), WithColumnNames(),
+		WithNullAs("''"), WithWhere("a", "id > 0"), WithFooter("PRAGMA optimize;"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, expect, b.String())
+	assert.Contains(t, b.String(), `INSERT INTO "a"("id","x") VALUES(2,'');`)
+	assert.NotContains(t, b.String(), "BEGIN TRANSACTION;")
+
+	// the zero Config is the default dump
+	b.Reset()
+	require.NoError(t, DumpWithConfig(dbName, &b, Config{}))
+	expect, err = DumpString(dbName)
+	require.NoError(t, err)
+	assert.Equal(t, expect, b.String())
+
+	b.Reset()
+	assert.EqualError(t, DumpWithConfig(dbName, &b, Config{IdentifierQuote: "``"}), `unsupported identifier quote '\x00'`)
+
+	// the functions aren't encoded
+	_, err = json.Marshal(Config{Warn: func(string) {}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,98 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DumpCSV dumps the rows of every table of a raw sql.DB into a <table>.csv file in dir,
+// which must exist, starting with a header row of the column names. Slashes and backslashes
+// in table names are replaced by underscores in the file names, an error is returned for
+// two tables of the same file name, such as "a/b" and "a_b", once the first file is written.
+//
+// NULL is written as an empty field, or as the string of WithNullAs, and BLOBs as base64.
+// The options selecting the tables and rows apply.
+func DumpCSV(db *sql.DB, dir string, opts ...Option) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	s3d := New(opts...)
+	// the file names are compared in lowercase for the case-insensitive file systems
+	tables := map[string]string{}
+	return s3d.export(context.Background(), db, func(db preparer, schema schema) error {
+		fileName := csvFileName(schema.Name)
+		if table, ok := tables[strings.ToLower(fileName)]; ok {
+			return fmt.Errorf("tables %q and %q have the same CSV file %s", table, schema.Name, fileName)
+		}
+		tables[strings.ToLower(fileName)] = schema.Name
+		return s3d.writeCSV(db, filepath.Join(dir, fileName), schema)
+	})
+}
+
+// csvFileName returns the name of the CSV file of the table.
+func csvFileName(table string) string {
+	return strings.NewReplacer("/", "_", `\`, "_").Replace(table) + ".csv"
+}
+
+func (s3d *Dumper) writeCSV(db preparer, fileName string, schema schema) (err error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	w := csv.NewWriter(f)
+	start := func(columnNames []string) error {
+		return w.Write(columnNames)
+	}
+	row := func(values []interface{}) error {
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = s3d.csvField(v)
+		}
+		return w.Write(record)
+	}
+	if err = s3d.exportRows(context.Background(), db, schema, start, row); err != nil {
+		return
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return fmt.Errorf("failed to write the rows of table %q: %w", schema.Name, err)
+	}
+	return
+}
+
+// csvField formats a scanned value as a CSV field.
+func (s3d *Dumper) csvField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		if s3d.nullAs != nil {
+			return *s3d.nullAs
+		}
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
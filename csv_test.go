@@ -0,0 +1,92 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpCSV(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, price REAL, data BLOB)`,
+		`CREATE TABLE "a/b"(a INTEGER)`,
+		`CREATE TABLE skipped(a INTEGER)`,
+		`INSERT INTO t(name, price, data) VALUES('it''s "x", y', 1.5, x'00ff'), (NULL, NULL, NULL), ('multi
+line', 2, '')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, DumpCSV(db, dir, WithExcludeTables("skipped")))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"a_b.csv", "t.csv"}, names)
+
+	got, err := ioutil.ReadFile(filepath.Join(dir, "t.csv"))
+	require.NoError(t, err)
+	expect := "id,name,price,data\n" +
+		`1,"it's ""x"", y",1.5,AP8=` + "\n" +
+		"2,,,\n" +
+		"3,\"multi\nline\",2,\n"
+	assert.Equal(t, expect, string(got))
+
+	got, err = ioutil.ReadFile(filepath.Join(dir, "a_b.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(got))
+}
+
+func TestDumpCSVNullAs(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`INSERT INTO t VALUES(NULL, 'x')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, DumpCSV(db, dir, WithNullAs(`\N`)))
+	got, err := ioutil.ReadFile(filepath.Join(dir, "t.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n\\N,x\n", string(got))
+}
+
+func TestDumpCSVMissingDir(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = DumpCSV(db, filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestDumpCSVSameFileName(t *testing.T) {
+	db, err := sql.Open("sqlite3", createDB(t,
+		`CREATE TABLE "a/b"(v TEXT)`,
+		`CREATE TABLE "a_b"(v TEXT)`,
+		`INSERT INTO "a/b" VALUES('x')`,
+		`INSERT INTO "a_b" VALUES('y')`,
+	))
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	err = DumpCSV(db, dir)
+	assert.EqualError(t, err, `tables "a/b" and "a_b" have the same CSV file a_b.csv`)
+	got, err := ioutil.ReadFile(filepath.Join(dir, "a_b.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "v\nx\n", string(got))
+}
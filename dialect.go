@@ -0,0 +1,82 @@
+package sqlite3dump
+
+import (
+	"context"
+	"io"
+	"regexp"
+)
+
+// Dialect adapts the statements of a dump to another database engine, see WithDialect and
+// PostgresDialect. It only rewrites what the dump writes, which is SQL of SQLite otherwise.
+type Dialect interface {
+	// QuoteIdent returns the name of a table or a column as written in the INSERT
+	// and DROP statements, which must be that of the objects Create creates.
+	QuoteIdent(name string) string
+	// Value returns the literal of a value as scanned from SQLite, nil, int64, float64,
+	// string or []byte, for a column of the declared type, which is "" for none.
+	Value(v interface{}, declaredType string) string
+	// Create returns the CREATE statement of an object of the type ("table", "index",
+	// "trigger" or "view") from that of SQLite, without the semicolon ending it,
+	// or "" to leave the object out.
+	Create(objType, name, sql string) string
+	// AfterRows returns the statements written after the rows of the table whose CREATE
+	// statement of SQLite is sql, such as those updating a sequence, or "" for none.
+	AfterRows(table, sql string) string
+}
+
+// dialectToken matches the tokens of SQL the dialects rewrite: comments, string literals,
+// quoted identifiers, words, numbers and whitespace. What's left are single characters.
+var dialectToken = regexp.MustCompile("--[^\n]*|(?s:/\\*.*?\\*/)|'(?:[^']|'')*'|\"(?:[^\"]|\"\")*\"|`(?:[^`]|``)*`|\\[[^\\]]*\\]|[A-Za-z_][A-Za-z0-9_$]*|[0-9][0-9A-Za-z_.]*|\\s+")
+
+// sqlTokens splits the SQL into tokens, see dialectToken, whose concatenation is the SQL.
+func sqlTokens(sql string) []string {
+	var tokens []string
+	end := 0
+	for _, match := range dialectToken.FindAllStringIndex(sql, -1) {
+		for ; end < match[0]; end++ {
+			tokens = append(tokens, sql[end:end+1])
+		}
+		tokens = append(tokens, sql[match[0]:match[1]])
+		end = match[1]
+	}
+	for ; end < len(sql); end++ {
+		tokens = append(tokens, sql[end:end+1])
+	}
+	return tokens
+}
+
+// columnTypes returns the declared types of the columns whose values are inserted,
+// in the order of insertedColumnNames, for the Value of the dialect.
+func (s3d *Dumper) columnTypes(ctx context.Context, db preparer, schema schema) ([]string, error) {
+	columns, err := s3d.tableColumns(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	types := []string{}
+	for _, c := range columns {
+		if c.Hidden == 0 {
+			types = append(types, c.Type)
+		}
+	}
+	return types, nil
+}
+
+// dialectSkipReason is why the internal tables of SQLite dumped otherwise, sqlite_sequence
+// and the statistics tables, are left out with a dialect.
+const dialectSkipReason = "internal table of SQLite, left out with a dialect"
+
+// leftOutByDialect reports whether the dialect leaves the object out, its CREATE and DROP
+// statements.
+func (s3d *Dumper) leftOutByDialect(schema schema) bool {
+	return s3d.dialect != nil && s3d.dialect.Create(schema.Type, schema.Name, trimTerminator(schema.SQL)) == ""
+}
+
+// writeCreateStatement writes the CREATE statement of the object, unless the dialect
+// leaves it out.
+func (s3d *Dumper) writeCreateStatement(w io.Writer, schema schema) error {
+	statement := s3d.createStatement(schema)
+	if statement == "" {
+		return nil
+	}
+	return writeStatement(w, statement+";\n")
+}
@@ -1,24 +1,125 @@
 package sqlite3dump
 
 import (
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-type sqlite3dumper struct {
+// ErrDatabaseNotFound is returned when dumping a database file that doesn't exist.
+var ErrDatabaseNotFound = errors.New("database not found")
+
+// Dumper dumps databases with the options it was created with.
+// It isn't modified by dumping, so it can be reused for many databases.
+type Dumper struct {
 	migration           bool
 	dropIfExists        bool
 	wrapWithTransaction bool
+	schemaOnly          bool
+	dataOnly            bool
+	includeTables       map[string]string
+	excludeTables       map[string]string
+	includeTableGlobs   []string
+	excludeTableGlobs   []string
+	insertBatchSize     int
+	foreignKeysOff      bool
+	dependencyOrder     bool
+	where               map[string]string
+	progress            func(table string, rowsDumped int64)
+	stableOrder         bool
+	transactionMode     string
+	withoutSnapshot     bool
+	columnNames         bool
+	schema              string
+	ifNotExists         bool
+	concurrency         int
+	progressMu          sync.Mutex
+	headerComment       bool
+	headerText          string
+	sequences           bool
+	explicitSequences   bool
+	checkpointEvery     int
+	perTableSavepoints  bool
+	identifierQuote     rune
+	nullAs              *string
+	maxRowsPerTable     int
+	tableLimits         map[string]int
+	utf8BOM             bool
+	readOnly            bool
+	queryOnlyConn       bool
+	rowFilters          map[string]rowFilter
+	timeColumns         map[string]map[string]bool
+	withoutIndexes      bool
+	withoutTriggers     bool
+	withoutViews        bool
+	insertVerb          string
+	temp                bool
+	renameSchema        string
+	// beginText and commitText replace the transaction statements, see WithTransactionStatements
+	customTransaction bool
+	beginText         string
+	commitText        string
+	warn              func(msg string)
+	warnMu            sync.Mutex
+	logger            logger
+	validateOnly      bool
+	keysetColumns     map[string]string
+	lineEnding        string
+	beforeTable       func(table string) string
+	afterTable        func(table string) string
+	bufferSize        int
+	rowCountComments  bool
+	onlyTypes         map[string]bool
+	pragmaState       bool
+	pragmaStateNames  []string
+	busyAttempts      int
+	busyDelay         time.Duration
+	footers           []string
+	columnTransforms  map[string]map[string]func(v interface{}) interface{}
+	skipEmptyTables   bool
+	skipDanglingViews bool
+	maxBytes          int64
+	orderBy           map[string]string
+	// tableTargetSchemas maps the tables to the schemas of WithTableTargetSchema
+	tableTargetSchemas map[string]string
+	attachments        []attachment
+	// resumeTable and resumeAfter are the table and the rowid of WithResumeFrom
+	resumeTable    string
+	resumeAfter    int64
+	createRewriter func(objType, name, sql string) string
+	schemaQuery    string
+	dialect        Dialect
+	// onlyTableObjects leaves out the views and the triggers of views, set by DumpTable
+	onlyTableObjects bool
 }
 
-func newSqlite3Dumper(opts ...Option) *sqlite3dumper {
-	dumper := &sqlite3dumper{
+// rowFilter decides whether a row is dumped and returns its values, see WithRowFilter.
+type rowFilter func(cols []string, vals []interface{}) (include bool, out []interface{})
+
+// progressInterval is the number of rows between two calls of the progress callback.
+const progressInterval = 1000
+
+// New returns a Dumper configured with the options.
+func New(opts ...Option) *Dumper {
+	dumper := &Dumper{
 		wrapWithTransaction: true,
+		insertBatchSize:     1,
+		schema:              "main",
+		identifierQuote:     '"',
+		insertVerb:          "INSERT",
+		lineEnding:          "\n",
 	}
 
 	if len(opts) == 0 {
@@ -31,261 +132,1168 @@ func newSqlite3Dumper(opts ...Option) *sqlite3dumper {
 	return dumper
 }
 
+// validate reports options that can't be used together.
+func (s3d *Dumper) validate() error {
+	if s3d.dataOnly && s3d.schemaOnly {
+		return errors.New("WithDataOnly and WithoutData options can't be combined")
+	}
+	switch s3d.transactionMode {
+	case "", "DEFERRED", "IMMEDIATE", "EXCLUSIVE":
+	default:
+		return fmt.Errorf("unknown transaction mode %q", s3d.transactionMode)
+	}
+	switch s3d.insertVerb {
+	case "INSERT", "INSERT OR REPLACE", "INSERT OR IGNORE", "REPLACE":
+	default:
+		return fmt.Errorf("unknown insert verb %q", s3d.insertVerb)
+	}
+	switch s3d.lineEnding {
+	case "\n", "\r\n", "\r":
+	default:
+		return fmt.Errorf("unsupported line ending %q", s3d.lineEnding)
+	}
+	for schemaType := range s3d.onlyTypes {
+		switch schemaType {
+		case "table", "index", "trigger", "view":
+		default:
+			return fmt.Errorf("unknown object type %q", schemaType)
+		}
+	}
+	if err := validatePragmaState(s3d.pragmaStateNames); err != nil {
+		return err
+	}
+	for table := range s3d.orderBy {
+		if _, paginated := s3d.keysetColumns[table]; paginated {
+			return fmt.Errorf("WithOrderBy and WithKeysetPagination options can't be combined for table %q", table)
+		}
+	}
+	if s3d.resumeTable != "" {
+		table := strings.ToLower(s3d.resumeTable)
+		if _, paginated := s3d.keysetColumns[table]; paginated {
+			return fmt.Errorf("WithResumeFrom and WithKeysetPagination options can't be combined for table %q", s3d.resumeTable)
+		}
+		if _, ordered := s3d.orderBy[table]; ordered {
+			return fmt.Errorf("WithResumeFrom and WithOrderBy options can't be combined for table %q", s3d.resumeTable)
+		}
+	}
+	if s3d.temp && s3d.concurrency > 1 {
+		return errors.New("WithTemp and WithConcurrency options can't be combined")
+	}
+	if s3d.perTableSavepoints && s3d.wrapWithTransaction && s3d.checkpointEvery > 0 {
+		return errors.New("WithPerTableSavepoints and WithCheckpointEvery options can't be combined")
+	}
+	if s3d.dialect != nil && s3d.insertVerb != "INSERT" {
+		return fmt.Errorf("the insert verb %q can't be combined with the WithDialect option", s3d.insertVerb)
+	}
+	if s3d.identifierQuote != '"' && s3d.identifierQuote != '`' {
+		return fmt.Errorf("unsupported identifier quote %q", s3d.identifierQuote)
+	}
+	if err := s3d.checkOptionNames(); err != nil {
+		return err
+	}
+	if err := validateGlobs(s3d.includeTableGlobs); err != nil {
+		return err
+	}
+	return validateGlobs(s3d.excludeTableGlobs)
+}
+
+// checkOptionNames checks the names of the options quoted in the queries or the statements
+// of the dump with checkName.
+func (s3d *Dumper) checkOptionNames() error {
+	names := []string{s3d.schema, s3d.renameSchema}
+	for _, schema := range s3d.tableTargetSchemas {
+		names = append(names, schema)
+	}
+	for _, a := range s3d.attachments {
+		names = append(names, a.name)
+	}
+	for _, column := range s3d.keysetColumns {
+		names = append(names, column)
+	}
+	for _, columns := range s3d.timeColumns {
+		for column := range columns {
+			names = append(names, column)
+		}
+	}
+	for _, name := range names {
+		if err := checkName("option", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DumpMigration will dump the database in an SQL text format
 // and not include creation tables and will include table column names
 //
 // Deprecated, use WithMigration() option instead.
 func DumpMigration(db *sql.DB, out io.Writer) (err error) {
-	s3d := newSqlite3Dumper(WithMigration())
-	return s3d.dumpDB(db, out)
+	return New(WithMigration()).DumpDB(db, out)
 }
 
 // Dump will dump the database in an SQL text format into the specified io.Writer.
 // Ported from the Python equivalent: https://github.com/python/cpython/blob/3.6/Lib/sqlite3/dump.py.
-// Returns an error if the database doesn't exist.
+// Returns an error wrapping ErrDatabaseNotFound if the database doesn't exist.
 func Dump(dbName string, out io.Writer, opts ...Option) (err error) {
-	s3d := newSqlite3Dumper(opts...)
-	return s3d.dump(dbName, out)
+	return DumpContext(context.Background(), dbName, out, opts...)
+}
+
+// DumpContext is like Dump but stops dumping and returns ctx.Err() once the context is done.
+func DumpContext(ctx context.Context, dbName string, out io.Writer, opts ...Option) (err error) {
+	return New(opts...).DumpContext(ctx, dbName, out)
+}
+
+// Dump dumps the database in an SQL text format into the specified io.Writer.
+// Returns an error wrapping ErrDatabaseNotFound if the database doesn't exist.
+func (s3d *Dumper) Dump(dbName string, out io.Writer) (err error) {
+	return s3d.dump(context.Background(), dbName, out, out)
+}
+
+// DumpContext is like Dump but stops dumping and returns ctx.Err() once the context is done.
+func (s3d *Dumper) DumpContext(ctx context.Context, dbName string, out io.Writer) (err error) {
+	return s3d.dump(ctx, dbName, out, out)
+}
+
+// DumpSplit dumps the database like Dump, but writes every CREATE and DROP statement into
+// schemaOut and everything else, including the transaction wrapping, into dataOut.
+//
+// Applying schemaOut and then dataOut reconstructs the database. Note that triggers are
+// then created before the rows are inserted, so they fire while the data is loaded.
+func DumpSplit(dbName string, schemaOut, dataOut io.Writer, opts ...Option) (err error) {
+	return New(opts...).DumpSplit(dbName, schemaOut, dataOut)
+}
+
+// DumpSplit is like Dump but writes the schema and the data into separate writers,
+// see the package-level DumpSplit.
+func (s3d *Dumper) DumpSplit(dbName string, schemaOut, dataOut io.Writer) (err error) {
+	return s3d.dump(context.Background(), dbName, schemaOut, dataOut)
 }
 
-func (s3d *sqlite3dumper) dump(dbName string, out io.Writer) (err error) {
-	// return if doesn't exist
+func (s3d *Dumper) dump(ctx context.Context, dbName string, schemaOut, dataOut io.Writer) (err error) {
+	// return if doesn't exist, rather than creating it
 	if _, err = os.Stat(dbName); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrDatabaseNotFound, dbName)
+	} else if err != nil {
 		return
 	}
 
-	db, err := sql.Open("sqlite3", dbName)
+	// the connections of a database dumped by name never write, see WithQueryOnly
+	dsn := fileDSN(dbName, "_query_only=1")
+	if s3d.readOnly {
+		dsn = fileDSN(dbName, "mode=ro", "_query_only=1")
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return
 	}
 	defer db.Close()
 
-	return s3d.dumpDB(db, out)
+	// the database is only opened by the first query, which fails for a file that isn't
+	// a database, such as an encrypted or truncated one
+	if _, err = db.ExecContext(ctx, `SELECT count(*) FROM "sqlite_master"`); err != nil {
+		return fmt.Errorf("cannot read database %s: %w", dbName, err)
+	}
+
+	_, err = s3d.dumpDB(ctx, db, schemaOut, dataOut)
+	return
+}
+
+// fileDSN returns the URI of the database file with the parameters, its path escaped so that
+// none of its characters, such as '?' or '#', ends it.
+func fileDSN(dbName string, params ...string) string {
+	return "file:" + (&url.URL{Path: filepath.ToSlash(dbName)}).EscapedPath() + "?" + strings.Join(params, "&")
 }
 
 // DumpDB dumps a raw sql.DB
 func DumpDB(db *sql.DB, out io.Writer, opts ...Option) (err error) {
-	s3d := newSqlite3Dumper(opts...)
-	return s3d.dumpDB(db, out)
+	return DumpDBContext(context.Background(), db, out, opts...)
 }
 
-func (s3d *sqlite3dumper) dumpDB(db *sql.DB, out io.Writer) (err error) {
-	if s3d.wrapWithTransaction {
-		out.Write([]byte("BEGIN TRANSACTION;\n"))
+// DumpDBContext is like DumpDB but stops dumping and returns ctx.Err() once the context is done.
+func DumpDBContext(ctx context.Context, db *sql.DB, out io.Writer, opts ...Option) (err error) {
+	return New(opts...).DumpDBContext(ctx, db, out)
+}
+
+// DumpDB dumps a raw sql.DB
+func (s3d *Dumper) DumpDB(db *sql.DB, out io.Writer) (err error) {
+	return s3d.DumpDBContext(context.Background(), db, out)
+}
+
+// DumpDBContext is like DumpDB but stops dumping and returns ctx.Err() once the context is done.
+func (s3d *Dumper) DumpDBContext(ctx context.Context, db *sql.DB, out io.Writer) (err error) {
+	_, err = s3d.dumpDB(ctx, db, out, out)
+	return
+}
+
+// DumpTable dumps a single table of a raw sql.DB, its CREATE statement, rows, indexes and
+// triggers, leaving out everything else. Returns an error if the table doesn't exist.
+// The table replaces the tables of WithTables and WithTableGlob options.
+func DumpTable(db *sql.DB, out io.Writer, table string, opts ...Option) (err error) {
+	dumper := New(opts...)
+	dumper.includeTables = map[string]string{strings.ToLower(table): table}
+	dumper.includeTableGlobs = nil
+	dumper.onlyTableObjects = true
+	_, err = dumper.dumpDB(context.Background(), db, out, out)
+	return
+}
+
+// Stats counts the objects written by a dump.
+type Stats struct {
+	// Tables is the number of tables whose CREATE statement or rows were written.
+	Tables int
+	// Rows is the number of rows written as INSERT statements, including those of sqlite_sequence.
+	Rows     int64
+	Indexes  int
+	Triggers int
+	Views    int
+	// SkippedTables are the tables of the database left out of every dump, in the order
+	// they'd be dumped: the internal tables of SQLite and the shadow tables of virtual tables.
+	// The tables filtered out by the options aren't listed.
+	SkippedTables []SkippedTable
+	// ResumeTable and ResumeAfterRowid are where a dump that failed, such as on a write error,
+	// resumes with WithResumeFrom: the table it was dumping and the rowid of the last row of
+	// the table written, or math.MinInt64 for none. ResumeTable is "" when the dump can't
+	// resume, as its last table wasn't dumped in rowid order, see WithResumeFrom, and with
+	// WithConcurrency or WithBufferSize, whose rows aren't written in order.
+	ResumeTable      string
+	ResumeAfterRowid int64
+}
+
+// SkippedTable is a table left out of a dump, and why, see Stats.
+type SkippedTable struct {
+	Name   string
+	Reason string
+}
+
+// DumpDBStats dumps a raw sql.DB and returns the number of objects written, which leaves
+// out filtered tables.
+func DumpDBStats(db *sql.DB, out io.Writer, opts ...Option) (Stats, error) {
+	return New(opts...).dumpDB(context.Background(), db, out, out)
+}
+
+// DumpString dumps the database into a string.
+// On error the partially dumped string is returned along with the error.
+func DumpString(dbName string, opts ...Option) (string, error) {
+	var b strings.Builder
+	err := Dump(dbName, &b, opts...)
+	return b.String(), err
+}
+
+// DumpDBString dumps a raw sql.DB into a string.
+// On error the partially dumped string is returned along with the error.
+func DumpDBString(db *sql.DB, opts ...Option) (string, error) {
+	var b strings.Builder
+	err := DumpDB(db, &b, opts...)
+	return b.String(), err
+}
+
+// DumpGzip dumps the database gzip compressed into the specified io.Writer.
+// The gzip stream is closed even if dumping fails, so out is never left with a truncated stream.
+func DumpGzip(dbName string, out io.Writer, opts ...Option) (err error) {
+	gz := gzip.NewWriter(out)
+	defer func() {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return Dump(dbName, gz, opts...)
+}
+
+// NewDumpReader returns a reader of the dump of a raw sql.DB, which is produced as it's read
+// by a goroutine writing into a pipe. An error of the dump is returned by Read once the
+// dump written before it has been read.
+//
+// The goroutine blocks until everything is read, so the reader must be read to the end
+// or closed, which stops the dump.
+func NewDumpReader(db *sql.DB, opts ...Option) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		_, err := New(opts...).dumpDB(context.Background(), db, w, w)
+		w.CloseWithError(err)
+	}()
+	return r
+}
+
+// schemaPrefix returns the quoted name of the dumped schema followed by a dot,
+// for qualifying the tables queried.
+func (s3d *Dumper) schemaPrefix() string {
+	return quoteIdent(s3d.schema) + "."
+}
+
+// quoteIdent quotes the identifier for the queries of the dump, doubling the double quotes in it.
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// checkName returns an error for a name holding a NUL character, which the driver and the
+// sqlite3 shell read the statements up to, so that a name of a tampered sqlite_master doesn't
+// end a quoted name of the dump early and shift the quotes of the statements that follow.
+// No SQL statement can create such a name.
+func checkName(kind, name string) error {
+	if strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid %s name %q, it contains a NUL character", kind, name)
+	}
+	return nil
+}
+
+// dumpIdent quotes the identifier for the statements written to the dump,
+// with the quote of WithIdentifierQuote.
+func (s3d *Dumper) dumpIdent(name string) string {
+	if s3d.dialect != nil {
+		return s3d.dialect.QuoteIdent(name)
+	}
+	q := string(s3d.identifierQuote)
+	return q + strings.Replace(name, q, q+q, -1) + q
+}
+
+// targetIdent quotes the name of the object a written DROP, INSERT or DELETE statement targets,
+// qualified by the schema of WithTableTargetSchema or WithRenameSchema.
+func (s3d *Dumper) targetIdent(name string) string {
+	schema := s3d.targetSchema(name)
+	if schema == "" {
+		return s3d.dumpIdent(name)
 	}
+	return s3d.dumpIdent(schema) + "." + s3d.dumpIdent(name)
+}
 
-	// sqlite_master table contains the SQL CREATE statements for the database.
-	tableSchemas, err := s3d.getSchemas(db, `
-        SELECT "name", "type", "sql"
-        FROM "sqlite_master"
+// preparer is implemented by *sql.DB and *sql.Tx, so the queries of a dump can run in a transaction.
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+func (s3d *Dumper) dumpDB(ctx context.Context, db *sql.DB, schemaOut, dataOut io.Writer) (stats Stats, err error) {
+	if err = s3d.validate(); err != nil {
+		return stats, err
+	}
+	if s3d.validateOnly {
+		schemaOut, dataOut = io.Discard, io.Discard
+	} else if s3d.bufferSize > 0 {
+		var flush func() error
+		schemaOut, dataOut, flush = s3d.buffer(schemaOut, dataOut)
+		defer func() {
+			if flushErr := flush(); err == nil && flushErr != nil {
+				err = fmt.Errorf("failed to write the dump: %w", flushErr)
+			}
+		}()
+	}
+	// the bytes are counted as they're written out, with the line endings
+	if s3d.maxBytes > 0 {
+		limited := s3d.limitBytes(schemaOut, dataOut)
+		schemaOut, dataOut = limited[0], limited[1]
+	}
+	if s3d.lineEnding != "\n" {
+		schemaOut = &lineEndingWriter{w: schemaOut, lineEnding: s3d.lineEnding}
+		dataOut = &lineEndingWriter{w: dataOut, lineEnding: s3d.lineEnding}
+	}
+
+	defer func() {
+		if err == nil {
+			s3d.logInfo("dumped database", "tables", stats.Tables, "rows", stats.Rows, "indexes", stats.Indexes, "triggers", stats.Triggers, "views", stats.Views)
+		}
+	}()
+
+	// a transaction is bound to a single connection, the concurrent workers need the pool
+	if s3d.concurrency > 1 {
+		err = s3d.queryOnly(ctx, db, func(db beginner) (err error) {
+			stats, err = s3d.writeDump(ctx, db, schemaOut, dataOut)
+			return
+		})
+		return
+	}
+
+	err = s3d.inSnapshot(ctx, db, func(db preparer) (err error) {
+		stats, err = s3d.writeDump(ctx, db, schemaOut, dataOut)
+		return
+	})
+	return
+}
+
+// inSnapshot runs fn with the queries in a single read transaction, so what they read is a
+// consistent snapshot even if the database is written to meanwhile, unless WithoutSnapshot is set.
+// The transaction is that of the query only connection of WithQueryOnly, if set.
+func (s3d *Dumper) inSnapshot(ctx context.Context, db *sql.DB, fn func(db preparer) error) (err error) {
+	return s3d.queryOnly(ctx, db, func(db beginner) (err error) {
+		if s3d.withoutSnapshot {
+			return fn(db)
+		}
+
+		var tx *sql.Tx
+		for attempt := 1; ; attempt++ {
+			tx, err = db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+			if !s3d.retryBusy(ctx, err, attempt) {
+				break
+			}
+		}
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+				return
+			}
+			err = tx.Commit()
+		}()
+
+		return fn(tx)
+	})
+}
+
+// dumpedSchemas returns the tables and the other objects the dump selects, in the order they're
+// dumped, and whether the foreign keys of the tables form a cycle with WithDependencyOrder.
+func (s3d *Dumper) dumpedSchemas(ctx context.Context, db preparer) (tableSchemas, otherSchemas []schema, shadows map[string]string, cycle bool, err error) {
+	if s3d.schemaQuery != "" {
+		tableSchemas, otherSchemas, err = s3d.customSchemas(ctx, db)
+		if err != nil {
+			return
+		}
+	} else {
+		// sqlite_master table contains the SQL CREATE statements for the database.
+		tableSchemas, err = s3d.getSchemas(ctx, db, `
+        SELECT "name", "type", "tbl_name", "sql"
+        FROM `+s3d.schemaPrefix()+`"sqlite_master"
             WHERE "sql" NOT NULL AND
             "type" == 'table'
             ORDER BY "name"
 		`)
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return
+		}
 
-	// Now when the type is 'index', 'trigger', or 'view'
-	otherSchemas, err := s3d.getSchemas(db, `
-		SELECT "name", "type", "sql"
-        FROM "sqlite_master"
+		// Now when the type is 'index', 'trigger', or 'view'
+		otherSchemas, err = s3d.getSchemas(ctx, db, `
+		SELECT "name", "type", "tbl_name", "sql"
+        FROM `+s3d.schemaPrefix()+`"sqlite_master"
             WHERE "sql" NOT NULL AND
             "type" IN ('index', 'trigger', 'view')
 		`)
+		if err != nil {
+			return
+		}
+	}
+
+	if s3d.temp {
+		var tempTables, tempOthers []schema
+		tempTables, tempOthers, err = s3d.tempSchemas(ctx, db)
+		if err != nil {
+			return
+		}
+		tableSchemas = append(tableSchemas, tempTables...)
+		otherSchemas = append(otherSchemas, tempOthers...)
+	}
+
+	otherSchemas = sortOtherSchemas(otherSchemas)
+
+	// shadow tables are found before filtering, so they stay skipped when their virtual table is filtered out
+	shadows = shadowTables(tableSchemas)
+
+	tableSchemas, otherSchemas, err = s3d.filterTables(tableSchemas, otherSchemas)
 	if err != nil {
-		return err
+		return
 	}
 
-	if s3d.dropIfExists {
-		allSchemas := append(otherSchemas, tableSchemas...)
-		if err := s3d.writeDropStatements(out, allSchemas); err != nil {
-			return err
+	if s3d.dependencyOrder {
+		tableSchemas, cycle, err = s3d.sortByDependencies(ctx, db, tableSchemas)
+	}
+	return
+}
+
+func (s3d *Dumper) writeDump(ctx context.Context, db preparer, schemaOut, dataOut io.Writer) (stats Stats, err error) {
+	tableSchemas, otherSchemas, shadows, cycle, err := s3d.dumpedSchemas(ctx, db)
+	if err != nil {
+		return stats, err
+	}
+	// the alphabetical order is kept for cycles, which only restores with foreign keys off,
+	// a pragma of SQLite the dialects don't write
+	foreignKeysOff := (s3d.foreignKeysOff || cycle) && s3d.dialect == nil
+
+	// sqlite_sequence is dumped after all the other tables, see writeSequenceTable
+	tableSchemas, sequenceTable, hasSequences := withoutSequenceTable(tableSchemas)
+	if hasSequences && s3d.dialect != nil {
+		stats.SkippedTables = append(stats.SkippedTables, SkippedTable{Name: sequenceTable.Name, Reason: dialectSkipReason})
+		hasSequences = false
+	}
+	if tableSchemas, err = s3d.resumedTables(tableSchemas); err != nil {
+		return stats, err
+	}
+
+	if s3d.utf8BOM {
+		if err = writeStatement(dataOut, "\uFEFF"); err != nil {
+			return stats, err
+		}
+	}
+
+	if s3d.headerComment || s3d.headerText != "" {
+		header, err := s3d.header(ctx, db)
+		if err != nil {
+			return stats, err
+		}
+		// a line at a time, so each gets the line ending
+		for _, line := range strings.Split(strings.TrimSuffix(header, "\n"), "\n") {
+			if err = writeStatement(dataOut, line+"\n"); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	if s3d.pragmaState {
+		if err = s3d.writePragmaState(ctx, dataOut, db); err != nil {
+			return stats, err
 		}
 	}
 
+	// the dump only starts once there's a statement to write, so a dump of nothing
+	// isn't an empty transaction
+	start := &dumpStart{w: dataOut, statements: s3d.attachStatements()}
+	if foreignKeysOff {
+		start.statements = append(start.statements, "PRAGMA foreign_keys=OFF;\n")
+	}
+	if s3d.wrapWithTransaction {
+		start.statements = append(start.statements, s3d.beginStatement())
+	}
+	schemaOut, dataOut = start.writer(schemaOut), start.writer(dataOut)
+
+	// a migration adds rows to the existing tables, which mustn't be dropped
+	if s3d.dropIfExists && !s3d.dataOnly && !s3d.migration {
+		// the rows of the table a dump resumes from are already restored
+		droppedTables := tableSchemas
+		if len(droppedTables) > 0 && s3d.resumesFrom(droppedTables[0].Name) {
+			droppedTables = droppedTables[1:]
+		}
+		dropped, err := s3d.dropOrder(ctx, db, droppedTables, otherSchemas)
+		if err != nil {
+			return stats, err
+		}
+		if err = s3d.writeDropStatements(schemaOut, dropped); err != nil {
+			return stats, err
+		}
+	}
+
+	if hasSequences && s3d.explicitSequences && !s3d.dataOnly && !s3d.migration {
+		if err = s3d.writeSequenceTableCreate(schemaOut, sequenceTable); err != nil {
+			return stats, err
+		}
+	}
+
+	// the rows, and only them, are written through rowsOut
+	rowsOut := dataOut
+	if s3d.wrapWithTransaction && s3d.checkpointEvery > 0 {
+		rowsOut = &checkpointWriter{w: dataOut, every: s3d.checkpointEvery, begin: s3d.beginStatement(), commit: s3d.commitStatement()}
+	}
+
+	// the savepoint of a table is only written before its first statement, see beginSavepoint
+	savepoint := &dumpStart{w: dataOut, started: true}
+	if s3d.perTableSavepoints && s3d.wrapWithTransaction {
+		schemaOut, dataOut, rowsOut = savepoint.writer(schemaOut), savepoint.writer(dataOut), savepoint.writer(rowsOut)
+	}
+
+	empty := map[tableKey]bool{}
+	if s3d.skipEmptyTables && !s3d.schemaOnly {
+		checked := tableSchemas
+		if hasSequences {
+			checked = append([]schema{sequenceTable}, tableSchemas...)
+		}
+		if empty, err = s3d.emptyTables(ctx, db, checked, shadows); err != nil {
+			return stats, err
+		}
+	}
+
+	// the point to resume from is only known for the rows written in order, see Stats
+	var point resumePoint
+	defer func() {
+		stats.ResumeTable, stats.ResumeAfterRowid = point.table, point.afterRowid
+	}()
+	tracked := s3d.bufferSize == 0
+	writeRows := func(schema schema) (int64, error) {
+		return s3d.writeInsStmtsForTableRows(ctx, rowsOut, db, schema, &point)
+	}
+	if s3d.concurrency > 1 && !s3d.schemaOnly {
+		dataTables := []schema{}
+		for _, schema := range tableSchemas {
+			if !skippedTable(schema, shadows) && !empty[keyOf(schema)] {
+				dataTables = append(dataTables, schema)
+			}
+		}
+		var wait func()
+		tracked = false
+		writeRows, wait = s3d.dumpRowsConcurrently(ctx, db, dataTables, rowsOut)
+		defer wait()
+	}
+
+	analyzed := false
 	for _, schema := range tableSchemas {
-		if schema.Name == "sqlite_sequence" {
-			out.Write([]byte(`DELETE FROM "sqlite_sequence";` + "\n"))
-		} else if schema.Name == "sqlite3_stat1" {
-			out.Write([]byte(`ANALYZE "sqlite_master";` + "\n"))
-		} else if strings.HasPrefix(schema.Name, "sqlite_") {
+		if reason := skipReason(schema, shadows); reason != "" {
+			stats.SkippedTables = append(stats.SkippedTables, SkippedTable{Name: schema.Name, Reason: reason})
 			continue
-			// # NOTE: Virtual table support not implemented
-			// #elif sql.startswith('CREATE VIRTUAL TABLE'):
-			// #    qtable = table_name.replace("'", "''")
-			// #    yield("INSERT INTO sqlite_master(type,name,tbl_name,rootpage,sql)"\
-			// #        "VALUES('table','{0}','{0}',0,'{1}');".format(
-			// #        qtable,
-			// #        sql.replace("''")))
-		} else if strings.HasSuffix(schema.Name, "_segments") || strings.HasSuffix(schema.Name, "_segdir") || strings.HasSuffix(schema.Name, "_stat") || strings.HasSuffix(schema.Name, "_idx") || strings.HasSuffix(schema.Name, "_docsize") || strings.HasSuffix(schema.Name, "_config") || strings.HasSuffix(schema.Name, "_data") || strings.HasSuffix(schema.Name, "_content") {
-			// these suffixes for tables are from using FTS5, and they should be ignored
-			// because they are automatically created
+		}
+		if s3d.dialect != nil && statTableRegexp.MatchString(schema.Name) {
+			stats.SkippedTables = append(stats.SkippedTables, SkippedTable{Name: schema.Name, Reason: dialectSkipReason})
 			continue
+		}
+		s3d.logDebug("dumping table", "table", schema.Name)
+		s3d.beginSavepoint(savepoint, schema.Name)
+		if statTableRegexp.MatchString(schema.Name) {
+			// ANALYZE of sqlite_master, which has no index, creates the statistics tables
+			// without filling them, so the rows that follow can be inserted
+			if !analyzed {
+				if err = writeStatement(dataOut, "ANALYZE "+s3d.targetIdent("sqlite_master")+";\n"); err != nil {
+					return stats, err
+				}
+				analyzed = true
+			}
 		} else {
-			if !s3d.migration {
-				out.Write([]byte(fmt.Sprintf("%s;\n", schema.SQL)))
+			// Unlike the Python equivalent, virtual tables are recreated with their
+			// CREATE VIRTUAL TABLE statement rather than by writing into sqlite_master,
+			// their rows are then inserted through the module, which fills the shadow tables.
+			if !s3d.migration && !s3d.dataOnly && !s3d.resumesFrom(schema.Name) {
+				if s3d.rowCountComments {
+					comment, err := s3d.rowCountComment(ctx, db, schema)
+					if err != nil {
+						return stats, err
+					}
+					if err = writeStatement(schemaOut, comment); err != nil {
+						return stats, err
+					}
+				}
+				if err = s3d.writeCreateStatement(schemaOut, schema); err != nil {
+					return stats, err
+				}
+			}
+			if !(s3d.migration && s3d.schemaOnly) {
+				stats.Tables++
+			}
+		}
+
+		// the table, whose CREATE statement is written, is where a dump failing from now resumes
+		if tracked {
+			point = s3d.resumeStart(schema)
+		}
+
+		if s3d.schemaOnly || empty[keyOf(schema)] {
+			if err = s3d.releaseSavepoint(savepoint, schema.Name); err != nil {
+				return stats, err
+			}
+			s3d.logInfo("dumped table", "table", schema.Name, "rows", int64(0))
+			continue
+		}
+
+		if err = s3d.writeTableHook(dataOut, s3d.beforeTable, schema.Name); err != nil {
+			return stats, err
+		}
+		// Build the insert statement for each row of the current table
+		rowsDumped, err := writeRows(schema)
+		stats.Rows += rowsDumped
+		if err != nil {
+			return stats, err
+		}
+		if s3d.dialect != nil {
+			if after := s3d.dialect.AfterRows(schema.Name, schema.SQL); after != "" {
+				if err = writeStatement(dataOut, statementLine(after)); err != nil {
+					return stats, err
+				}
 			}
 		}
+		if err = s3d.writeTableHook(dataOut, s3d.afterTable, schema.Name); err != nil {
+			return stats, err
+		}
+		if err = s3d.releaseSavepoint(savepoint, schema.Name); err != nil {
+			return stats, err
+		}
+		s3d.logInfo("dumped table", "table", schema.Name, "rows", rowsDumped)
+	}
+
+	if hasSequences && !empty[keyOf(sequenceTable)] {
+		s3d.logDebug("dumping table", "table", sequenceTable.Name)
+		s3d.beginSavepoint(savepoint, sequenceTable.Name)
+		rowsDumped, err := s3d.writeSequenceTable(ctx, dataOut, rowsOut, db, sequenceTable)
+		stats.Rows += rowsDumped
+		if err != nil {
+			return stats, err
+		}
+		if err = s3d.releaseSavepoint(savepoint, sequenceTable.Name); err != nil {
+			return stats, err
+		}
+		s3d.logInfo("dumped table", "table", sequenceTable.Name, "rows", rowsDumped)
+	}
 
-		// // Build the insert statement for each row of the current table
-		// schema.Name = strings.Replace(schema.Name, `"`, `""`, -1)
-		// err = s3d.writeInsStmtsForTableRows(out, db, schema.Name)
-		// if err != nil {
-		// 	return err
-		// }
+	if !s3d.dataOnly {
+		for _, schema := range otherSchemas {
+			if s3d.leftOutByDialect(schema) {
+				continue
+			}
+			if err = s3d.writeCreateStatement(schemaOut, schema); err != nil {
+				return stats, err
+			}
+			switch schema.Type {
+			case "index":
+				stats.Indexes++
+			case "trigger":
+				stats.Triggers++
+			case "view":
+				stats.Views++
+			}
+		}
 	}
 
-	for _, schema := range otherSchemas {
-		out.Write([]byte(fmt.Sprintf("%s;\n", schema.SQL)))
+	if !start.started {
+		return
 	}
 
 	if s3d.wrapWithTransaction {
-		out.Write([]byte("COMMIT;\n"))
+		if err = writeStatement(dataOut, s3d.commitStatement()); err != nil {
+			return stats, err
+		}
+	}
+
+	if foreignKeysOff {
+		if err = writeStatement(dataOut, "PRAGMA foreign_keys=ON;\n"); err != nil {
+			return stats, err
+		}
+	}
+
+	// outside of the transaction, which VACUUM can't run in
+	for _, footer := range s3d.footers {
+		if err = writeStatement(dataOut, statementLine(footer)); err != nil {
+			return stats, err
+		}
+	}
+
+	for _, a := range s3d.attachments {
+		if err = writeStatement(dataOut, "DETACH DATABASE "+s3d.dumpIdent(a.name)+";\n"); err != nil {
+			return stats, err
+		}
 	}
 
 	return
 }
 
-func (s3d *sqlite3dumper) writeDropStatements(w io.Writer, schemas []schema) (err error) {
+// writeTableHook writes the SQL the WithBeforeTable or WithAfterTable hook returns for the table.
+func (s3d *Dumper) writeTableHook(w io.Writer, hook func(table string) string, table string) error {
+	if hook == nil {
+		return nil
+	}
+	sql := hook(table)
+	if sql == "" {
+		return nil
+	}
+	return writeStatement(w, statementLine(sql))
+}
+
+// beginStatement returns the statement beginning the transaction of the dump.
+func (s3d *Dumper) beginStatement() string {
+	if s3d.customTransaction {
+		return statementLine(s3d.beginText)
+	}
+	if s3d.transactionMode != "" {
+		return fmt.Sprintf("BEGIN %s TRANSACTION;\n", s3d.transactionMode)
+	}
+	return "BEGIN TRANSACTION;\n"
+}
+
+// commitStatement returns the statement ending the transaction of the dump.
+func (s3d *Dumper) commitStatement() string {
+	if s3d.customTransaction {
+		return statementLine(s3d.commitText)
+	}
+	return "COMMIT;\n"
+}
+
+// statementLine returns the statement followed by a newline, or nothing for an empty statement.
+func statementLine(statement string) string {
+	if statement == "" || strings.HasSuffix(statement, "\n") {
+		return statement
+	}
+	return statement + "\n"
+}
+
+// writeStatement writes the statement, wrapping a write error with the statement that failed.
+func writeStatement(w io.Writer, statement string) error {
+	if _, err := w.Write([]byte(statement)); err != nil {
+		return fmt.Errorf("failed to write %q: %w", strings.TrimSuffix(statement, "\n"), err)
+	}
+	return nil
+}
+
+// statTableRegexp matches the tables of the statistics gathered by ANALYZE.
+var statTableRegexp = regexp.MustCompile(`^sqlite_stat[1-4]$`)
+
+// skippedTable reports whether the table is left out of the dump entirely: the tables
+// SQLite uses internally, other than sqlite_sequence and the statistics tables, and shadow tables.
+func skippedTable(schema schema, shadows map[string]string) bool {
+	return skipReason(schema, shadows) != ""
+}
+
+// skipReason returns why the table is left out of the dump entirely, see skippedTable,
+// or "" if it isn't.
+func skipReason(schema schema, shadows map[string]string) string {
+	if schema.Name == "sqlite_sequence" || statTableRegexp.MatchString(schema.Name) {
+		return ""
+	}
+	if strings.HasPrefix(schema.Name, "sqlite_") {
+		return "internal table of SQLite"
+	}
+	// shadow tables of FTS and R-Tree virtual tables should be ignored
+	// because they are automatically created along with the virtual table
+	if virtualTable, ok := shadows[strings.ToLower(schema.Name)]; ok {
+		return fmt.Sprintf("shadow table of virtual table %q, which creates it", virtualTable)
+	}
+	return ""
+}
+
+// writeDropStatements writes a DROP ... IF EXISTS statement for each of the objects,
+// in the order of dropOrder, once even if objects of the main and temp schemas share a name.
+func (s3d *Dumper) writeDropStatements(w io.Writer, schemas []schema) (err error) {
+	written := map[string]bool{}
 	for _, schema := range schemas {
 		var statement string
 
 		switch schema.Type {
 		case "index":
-			statement = fmt.Sprintf("DROP INDEX IF EXISTS %s;\n", schema.Name)
+			if strings.HasPrefix(schema.Name, "sqlite_autoindex_") {
+				// the indexes of UNIQUE and PRIMARY KEY constraints go with their table
+				continue
+			}
+			statement = fmt.Sprintf("DROP INDEX IF EXISTS %s;\n", s3d.targetIdent(schema.Name))
 		case "table":
 			if strings.HasPrefix(schema.Name, "sqlite_") {
 				// skip system tables
 				continue
 			}
 
-			statement = fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", schema.Name)
+			statement = fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", s3d.targetIdent(schema.Name))
+		case "view":
+			statement = fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", s3d.targetIdent(schema.Name))
+		case "trigger":
+			statement = fmt.Sprintf("DROP TRIGGER IF EXISTS %s;\n", s3d.targetIdent(schema.Name))
 		default:
 			continue
 		}
 
-		_, err = w.Write([]byte(statement))
-		if err != nil {
-			return fmt.Errorf("failed to write '%q': %s", statement, err)
+		if written[statement] || s3d.leftOutByDialect(schema) {
+			continue
+		}
+		written[statement] = true
+		if err = writeStatement(w, statement); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (s3d *sqlite3dumper) writeInsStmtsForTableRows(w io.Writer, db *sql.DB, tableName string) (err error) {
-	// first get the column names
-	columnNames, err := s3d.pragmaTableInfo(db, tableName)
+func (s3d *Dumper) writeInsStmtsForTableRows(ctx context.Context, w io.Writer, db preparer, schema schema, point *resumePoint) (rowsDumped int64, err error) {
+	table := schema.Name
+	filter := s3d.rowFilters[strings.ToLower(table)]
+	scan := s3d.scanValues(table)
+	keyColumn, paginated := s3d.keysetColumns[strings.ToLower(table)]
+
+	var q, nextPage string
+	var columnNames []string
+	// the rowid of the rows is selected after them, for the point to resume from
+	var rowid string
+	if point != nil && point.table == table {
+		if rowid, err = s3d.resumeRowid(ctx, db, schema); err != nil {
+			return
+		}
+		if rowid == "" {
+			*point = resumePoint{}
+		}
+	}
+	if paginated {
+		q, nextPage, columnNames, err = s3d.keysetQueries(ctx, db, schema, scan, keyColumn)
+	} else if rowid != "" {
+		q, columnNames, err = s3d.rowQuery(ctx, db, schema, scan, true, "+"+rowid)
+	} else {
+		q, columnNames, err = s3d.rowQuery(ctx, db, schema, scan, true)
+	}
 	if err != nil {
 		return
 	}
+	var columnTypes []string
+	if s3d.dialect != nil {
+		if columnTypes, err = s3d.columnTypes(ctx, db, schema); err != nil {
+			return
+		}
+	}
 
-	// sqlite_master table contains the SQL CREATE statements for the database.
-	columnSelects := make([]string, len(columnNames))
-	for i, c := range columnNames {
-		columnSelects[i] = fmt.Sprintf(`'||quote("%s")||'`, strings.Replace(c, `"`, `""`, -1))
+	prefix := fmt.Sprintf(`%s INTO %s VALUES`, s3d.insertVerb, s3d.targetIdent(table))
+	if s3d.columnNames {
+		quotedNames := make([]string, len(columnNames))
+		for i, c := range columnNames {
+			quotedNames[i] = s3d.dumpIdent(c)
+		}
+		prefix = fmt.Sprintf(`%s INTO %s(%s) VALUES`, s3d.insertVerb, s3d.targetIdent(table), strings.Join(quotedNames, ","))
 	}
 
-	q := fmt.Sprintf(`
-		SELECT 'INSERT INTO "%s" VALUES(%s)' FROM "%s";
-	`,
-		tableName,
-		strings.Join(columnSelects, ","),
-		tableName,
-	)
-	if s3d.migration {
-		q = fmt.Sprintf(`
-		SELECT 'INSERT INTO "%s"(%s) VALUES(%s)' FROM "%s";
-	`,
-			tableName,
-			strings.Join(columnNames, ","),
-			strings.Join(columnSelects, ","),
-			tableName,
-		)
+	// up to insertBatchSize rows are combined into a single INSERT statement
+	batch := make([]string, 0, s3d.insertBatchSize)
+	// batchRowid is the rowid of the last row of the batch
+	var batchRowid int64
+	flush := func() (err error) {
+		if len(batch) == 0 {
+			return
+		}
+		_, err = w.Write([]byte(fmt.Sprintf("%s%s;\n", prefix, strings.Join(batch, ","))))
+		if err != nil {
+			err = fmt.Errorf("failed to write the rows of table %q: %w", table, err)
+		} else if rowid != "" {
+			point.afterRowid = batchRowid
+		}
+		batch = batch[:0]
+		return
+	}
+
+	// writeRows writes the rows the query selects and returns how many it read, including those
+	// left out by the row filter, and the key selected after the values of the last one, if paginated
+	writeRows := func(q string, args ...interface{}) (rowsRead int, lastKey interface{}, err error) {
+		stmt, err := db.PrepareContext(ctx, q)
+		if err != nil {
+			return
+		}
+		defer stmt.Close()
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+
+		var key []interface{}
+		if paginated {
+			key = []interface{}{&lastKey}
+		} else if rowid != "" {
+			key = []interface{}{&batchRowid}
+		}
+		for rows.Next() {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			rowsRead++
+			var values string
+			if scan {
+				var include bool
+				values, include, err = s3d.scanRow(rows, table, columnNames, columnTypes, filter, key...)
+				if err == nil && !include {
+					continue
+				}
+			} else {
+				err = rows.Scan(append([]interface{}{&values}, key...)...)
+				values = quotedInfinities(values)
+			}
+			if err != nil {
+				return
+			}
+			batch = append(batch, values)
+			if len(batch) >= s3d.insertBatchSize {
+				if err = flush(); err != nil {
+					return
+				}
+			}
+			rowsDumped++
+			if rowsDumped%progressInterval == 0 {
+				s3d.reportProgress(table, rowsDumped)
+			}
+		}
+		err = rows.Err()
+		return
+	}
+
+	// a query that failed before any of its rows was written can run again, see WithBusyRetry
+	queryRows := func(q string, args ...interface{}) (rowsRead int, lastKey interface{}, err error) {
+		for attempt := 1; ; attempt++ {
+			rowsRead, lastKey, err = writeRows(q, args...)
+			if rowsRead > 0 || !s3d.retryBusy(ctx, err, attempt) {
+				return
+			}
+		}
 	}
 
-	stmt, err := db.Prepare(q)
+	rowsRead, lastKey, err := queryRows(q)
+	// a page with fewer rows than the page size is the last one
+	for paginated && err == nil && rowsRead == s3d.pageSize(schema.Name) {
+		rowsRead, lastKey, err = queryRows(nextPage, lastKey)
+	}
 	if err != nil {
 		return
 	}
-	defer stmt.Close()
-	rows, err := stmt.Query()
+	if err = flush(); err != nil {
+		return
+	}
+	s3d.reportProgress(table, rowsDumped)
+	return
+}
+
+// rowQuery returns the query selecting the rows of the table, and the names of the columns
+// it selects. Each row is a single value, the parenthesized values formatted by quote(),
+// or the values of the columns for the scan-based path. With resume, the rows of the table
+// of WithResumeFrom are those after its rowid.
+func (s3d *Dumper) rowQuery(ctx context.Context, db preparer, schema schema, scan, resume bool, extra ...string) (q string, columnNames []string, err error) {
+	condition, hasCondition := s3d.where[strings.ToLower(schema.Name)]
+
+	q, allColumns, columnNames, err := s3d.rowSelect(ctx, db, schema, scan, extra...)
 	if err != nil {
 		return
 	}
-	defer rows.Close()
+	if resume && s3d.resumesFrom(schema.Name) {
+		// the rows after the rowid, in its order, see WithResumeFrom
+		rowid := stableOrder(schema, allColumns)
+		if hasCondition {
+			q += "WHERE (" + condition + ") AND "
+		} else {
+			q += "WHERE "
+		}
+		q += fmt.Sprintf("%s > %d ORDER BY %s", rowid, s3d.resumeAfter, rowid)
+	} else {
+		if hasCondition {
+			q += "WHERE " + condition
+		}
+		if order, hasOrder := s3d.orderBy[strings.ToLower(schema.Name)]; hasOrder {
+			q += " ORDER BY " + order
+		} else if s3d.stableOrder {
+			q += " ORDER BY " + stableOrder(schema, allColumns)
+		}
+	}
+	if limit := s3d.rowLimit(schema.Name); limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return
+}
 
-	for rows.Next() {
-		var insert string
-		err = rows.Scan(&insert)
-		if err != nil {
-			return
+// rowSelect returns the SELECT and FROM clauses of the row query, see rowQuery, with the
+// extra expressions selected after the row, the columns of the table and the names of
+// the selected ones.
+func (s3d *Dumper) rowSelect(ctx context.Context, db preparer, schema schema, scan bool, extra ...string) (q string, allColumns []column, columnNames []string, err error) {
+	table := schema.Name
+
+	// first get the column names
+	allColumns, err = s3d.pragmaTableXInfo(ctx, db, schema)
+	if err != nil {
+		return
+	}
+	columnNames = insertedColumnNames(allColumns)
+
+	var selects []string
+	if scan {
+		selects = s3d.valueSelects(table, columnNames)
+	} else {
+		columnSelects := make([]string, len(columnNames))
+		for i, c := range columnNames {
+			columnSelects[i] = fmt.Sprintf(`'||quote(%s)||'`, s3d.columnValue(table, c))
 		}
-		_, err = w.Write([]byte(fmt.Sprintf("%s;\n", insert)))
-		if err != nil {
-			return
+		selects = []string{fmt.Sprintf(`'(%s)'`, strings.Join(columnSelects, ","))}
+	}
+	q = fmt.Sprintf(`
+		SELECT %s FROM %s%s
+	`,
+		strings.Join(append(selects, extra...), ","),
+		s3d.tablePrefix(schema),
+		quoteIdent(table),
+	)
+	return
+}
+
+// reportProgress calls the progress callback, never concurrently.
+func (s3d *Dumper) reportProgress(table string, rowsDumped int64) {
+	if s3d.progress == nil {
+		return
+	}
+	s3d.progressMu.Lock()
+	defer s3d.progressMu.Unlock()
+	s3d.progress(table, rowsDumped)
+}
+
+// column is a row of PRAGMA table_xinfo.
+type column struct {
+	Name string
+	Type string
+	// PK is the 1-based position of the column in the primary key, 0 if it isn't part of it.
+	PK int
+	// Hidden is 1 for the hidden columns of virtual tables, 2 and 3 for the virtual and
+	// stored generated columns, and 0 for the others.
+	Hidden int
+}
+
+// insertedColumnNames returns the names of the columns whose values are inserted, leaving out
+// the generated columns, which can't be inserted into, and the hidden columns of virtual tables.
+func insertedColumnNames(columns []column) []string {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.Hidden == 0 {
+			names = append(names, c.Name)
 		}
 	}
-	return rows.Err()
+	return names
 }
 
-func (s3d *sqlite3dumper) pragmaTableInfo(db *sql.DB, tableName string) (columnNames []string, err error) {
-	// sqlite_master table contains the SQL CREATE statements for the database.
+func (s3d *Dumper) pragmaTableXInfo(ctx context.Context, db preparer, schema schema) (columns []column, err error) {
+	if columns, err = s3d.tableColumns(ctx, db, schema); err != nil {
+		return
+	}
+	s3d.warnColumnTypes(schema.Name, columns)
+	return
+}
+
+// tableColumns returns the columns of the table, like pragmaTableXInfo without the warnings.
+func (s3d *Dumper) tableColumns(ctx context.Context, db preparer, schema schema) (columns []column, err error) {
+	// unlike table_info, table_xinfo reports the generated columns
 	q := `
-        PRAGMA table_info("` + tableName + `")
+        PRAGMA ` + s3d.tablePrefix(schema) + `table_xinfo(` + quoteIdent(schema.Name) + `)
 		`
-	stmt, err := db.Prepare(q)
+	stmt, err := db.PrepareContext(ctx, q)
 	if err != nil {
 		return
 	}
 	defer stmt.Close()
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
-	columnNames = []string{}
+	columns = []column{}
 	for rows.Next() {
-		var arr []interface{}
-		for i := 0; i < 6; i++ {
-			arr = append(arr, new(interface{}))
-		}
-		err = rows.Scan(arr...)
+		var (
+			c            column
+			cid, notNull int
+			defaultValue interface{}
+		)
+		err = rows.Scan(&cid, &c.Name, &c.Type, &notNull, &defaultValue, &c.PK, &c.Hidden)
 		if err != nil {
 			return
 		}
-		columnNames = append(columnNames,
-			func() (result string) {
-				// check the type
-				switch (*arr[1].(*interface{})).(type) {
-				case string:
-					result = string((*arr[1].(*interface{})).(string))
-				case []uint8:
-					result = string((*arr[1].(*interface{})).([]uint8))
-				}
-				return
-			}(),
-		)
+		if err = checkName("column", c.Name); err != nil {
+			return
+		}
+		columns = append(columns, c)
 	}
 	err = rows.Err()
 	return
 }
 
 type schema struct {
-	Name string
-	Type string
-	SQL  string
+	Name      string
+	Type      string
+	TableName string
+	SQL       string
+	// Temp is set for the objects of the temp schema, see WithTemp.
+	Temp bool
 }
 
-func (s3d *sqlite3dumper) getSchemas(db *sql.DB, q string) (schemas []schema, err error) {
-	stmt, err := db.Prepare(q)
+func (s3d *Dumper) getSchemas(ctx context.Context, db preparer, q string) (schemas []schema, err error) {
+	for attempt := 1; ; attempt++ {
+		schemas, err = s3d.querySchemas(ctx, db, q)
+		if !s3d.retryBusy(ctx, err, attempt) {
+			return
+		}
+	}
+}
+
+func (s3d *Dumper) querySchemas(ctx context.Context, db preparer, q string) (schemas []schema, err error) {
+	stmt, err := db.PrepareContext(ctx, q)
 	if err != nil {
 		return
 	}
 	defer stmt.Close()
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return
 	}
@@ -294,10 +1302,16 @@ func (s3d *sqlite3dumper) getSchemas(db *sql.DB, q string) (schemas []schema, er
 	schemas = []schema{}
 	for rows.Next() {
 		s := schema{}
-		err = rows.Scan(&s.Name, &s.Type, &s.SQL)
+		err = rows.Scan(&s.Name, &s.Type, &s.TableName, &s.SQL)
 		if err != nil {
 			return
 		}
+		if err = checkName(s.Type, s.Name); err != nil {
+			return
+		}
+		if err = checkName("table", s.TableName); err != nil {
+			return
+		}
 		schemas = append(schemas, s)
 	}
 	err = rows.Err()
@@ -3,8 +3,14 @@ package sqlite3dump
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -19,6 +25,31 @@ func assertEqualIgnoreLineSeparators(t *testing.T, expected []byte, actual []byt
 	assert.Equal(t, expectedStr, actualStr)
 }
 
+// createDB creates a database in a temporary directory by executing the given statements.
+func createDB(t testing.TB, statements ...string) string {
+	t.Helper()
+	dbName := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, stmt := range statements {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	return dbName
+}
+
+// restoreDB executes the dump in a new database and returns it.
+func restoreDB(t *testing.T, dump string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "restored.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(dump)
+	require.NoError(t, err, dump)
+	return db
+}
+
 func TestCars(t *testing.T) {
 	var b bytes.Buffer
 	out := bufio.NewWriter(&b)
@@ -71,6 +102,51 @@ func TestDump(t *testing.T) {
 			expectFile: "without_tx.sql",
 			options:    []Option{WithTransaction(false)},
 		},
+		"WithoutData": {
+			dbFile:     "cars.db",
+			expectFile: "without_data.sql",
+			options:    []Option{WithoutData()},
+		},
+		"WithoutData and WithMigration": {
+			dbFile:     "cars.db",
+			expectFile: "without_data_migrate.sql",
+			options:    []Option{WithoutData(), WithMigration()},
+		},
+		"WithMigration and WithDropIfExists": {
+			dbFile:     "cars.db",
+			expectFile: "migrate.sql",
+			options:    []Option{WithMigration(), WithDropIfExists(true)},
+		},
+		"WithoutData, WithMigration and WithDropIfExists": {
+			dbFile:     "cars.db",
+			expectFile: "without_data_migrate.sql",
+			options:    []Option{WithoutData(), WithMigration(), WithDropIfExists(true)},
+		},
+		"WithDataOnly": {
+			dbFile:     "cars.db",
+			expectFile: "data_only.sql",
+			options:    []Option{WithDataOnly(), WithDropIfExists(true)},
+		},
+		"WithInsertBatchSize": {
+			dbFile:     "cars.db",
+			expectFile: "insert_batch.sql",
+			options:    []Option{WithInsertBatchSize(3)},
+		},
+		"WithInsertBatchSize and WithMigration": {
+			dbFile:     "cars.db",
+			expectFile: "insert_batch_migrate.sql",
+			options:    []Option{WithInsertBatchSize(4), WithMigration()},
+		},
+		"WithInsertBatchSize - 1": {
+			dbFile:     "cars.db",
+			expectFile: "python.sql",
+			options:    []Option{WithInsertBatchSize(1)},
+		},
+		"WithoutData and WithDropIfExists": {
+			dbFile:     "cars.db",
+			expectFile: "without_data_drop_if_exists.sql",
+			options:    []Option{WithoutData(), WithDropIfExists(true)},
+		},
 	}
 
 	for name, c := range cases {
@@ -90,3 +166,1055 @@ func TestDump(t *testing.T) {
 		})
 	}
 }
+
+func TestWithoutDataKeepsOtherSchemas(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`INSERT INTO t VALUES(1, 'one')`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+	)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithoutData())
+	require.NoError(t, err)
+
+	got := b.String()
+	assert.NotContains(t, got, "INSERT INTO")
+	assert.Contains(t, got, "CREATE TABLE t(a INTEGER, b TEXT);\n")
+	assert.Contains(t, got, "CREATE INDEX t_a ON t(a);\n")
+	assert.Contains(t, got, "CREATE VIEW v AS SELECT a FROM t;\n")
+	assert.Contains(t, got, "CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END;\n")
+}
+
+func TestWithMigrationKeepsOtherSchemas(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+	)
+
+	got, err := DumpString(dbName, WithMigration(), WithDropIfExists(true))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t"("a") VALUES(1);` + "\n" +
+		"CREATE INDEX t_a ON t(a);\n" +
+		"CREATE VIEW v AS SELECT a FROM t;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithDataOnly(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT)`,
+		`INSERT INTO t(b) VALUES('one')`,
+		`CREATE INDEX t_b ON t(b)`,
+		`CREATE VIEW v AS SELECT b FROM t`,
+	)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithDataOnly())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t" VALUES(1,'one');` + "\n" +
+		`DELETE FROM "sqlite_sequence";` + "\n" +
+		`INSERT INTO "sqlite_sequence" VALUES('t',1);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, b.String())
+}
+
+func TestWithDataOnlyAndWithoutData(t *testing.T) {
+	var b bytes.Buffer
+	err := Dump("testdata/cars.db", &b, WithDataOnly(), WithoutData())
+	assert.Error(t, err)
+}
+
+// hookWriter calls hook the first time a write contains the trigger string.
+type hookWriter struct {
+	bytes.Buffer
+	trigger string
+	hook    func()
+	called  bool
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	if !w.called && strings.Contains(string(p), w.trigger) {
+		w.called = true
+		w.hook()
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestDumpContextCancelMidTable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &hookWriter{trigger: "INSERT INTO", hook: cancel}
+	err := DumpContext(ctx, "testdata/cars.db", out)
+	assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	assert.Equal(t, 1, strings.Count(out.String(), "INSERT INTO"))
+	assert.NotContains(t, out.String(), "COMMIT;")
+}
+
+func TestDumpDBContextCanceled(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var b bytes.Buffer
+	err = DumpDBContext(ctx, db, &b)
+	assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+}
+
+func TestDumpString(t *testing.T) {
+	expect, err := ioutil.ReadFile("testdata/python.sql")
+	require.NoError(t, err)
+
+	got, err := DumpString("testdata/cars.db")
+	require.NoError(t, err)
+	assertEqualIgnoreLineSeparators(t, expect, []byte(got))
+
+	_, err = DumpString("testdata/cars.db", WithDataOnly(), WithoutData())
+	assert.Error(t, err)
+}
+
+func TestDumpDBString(t *testing.T) {
+	expect, err := ioutil.ReadFile("testdata/migrate.sql")
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	got, err := DumpDBString(db, WithMigration())
+	require.NoError(t, err)
+	assertEqualIgnoreLineSeparators(t, expect, []byte(got))
+}
+
+func TestDumpDBStringPartial(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	got, err := DumpDBString(db, WithTables("missing"))
+	assert.Error(t, err)
+	assert.Empty(t, got)
+}
+
+func TestWithForeignKeysOff(t *testing.T) {
+	cases := map[string]struct {
+		options []Option
+		expect  string
+	}{
+		"with transaction": {
+			options: []Option{WithForeignKeysOff(), WithoutData()},
+			expect: "PRAGMA foreign_keys=OFF;\n" +
+				"BEGIN TRANSACTION;\n" +
+				"CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n" +
+				"COMMIT;\n" +
+				"PRAGMA foreign_keys=ON;\n",
+		},
+		"without transaction": {
+			options: []Option{WithForeignKeysOff(), WithoutData(), WithTransaction(false)},
+			expect: "PRAGMA foreign_keys=OFF;\n" +
+				"CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n" +
+				"PRAGMA foreign_keys=ON;\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString("testdata/cars.db", c.options...)
+			require.NoError(t, err)
+			assert.Equal(t, c.expect, got)
+		})
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "progress.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE big(n INTEGER);
+		WITH RECURSIVE seq(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM seq WHERE n < 2500)
+		INSERT INTO big SELECT n FROM seq;
+		CREATE TABLE small(n INTEGER);
+		INSERT INTO small VALUES(1);
+	`)
+	require.NoError(t, err)
+
+	type call struct {
+		table string
+		rows  int64
+	}
+	var calls []call
+	err = DumpDB(db, ioutil.Discard, WithProgress(func(table string, rowsDumped int64) {
+		calls = append(calls, call{table, rowsDumped})
+	}))
+	require.NoError(t, err)
+
+	expect := []call{{"big", 1000}, {"big", 2000}, {"big", 2500}, {"small", 1}}
+	assert.Equal(t, expect, calls)
+}
+
+func TestDumpGzip(t *testing.T) {
+	expect, err := ioutil.ReadFile("testdata/python.sql")
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	err = DumpGzip("testdata/cars.db", &b)
+	require.NoError(t, err)
+
+	gz, err := gzip.NewReader(&b)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+	assertEqualIgnoreLineSeparators(t, expect, got)
+}
+
+func TestDumpGzipErrorClosesStream(t *testing.T) {
+	var b bytes.Buffer
+	err := DumpGzip("testdata/cars.db", &b, WithDataOnly(), WithoutData())
+	assert.Error(t, err)
+
+	gz, err := gzip.NewReader(&b)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+}
+
+func TestDumpDBStats(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, n INTEGER)`,
+		`CREATE TABLE b(n INTEGER)`,
+		`CREATE TABLE skipped(n INTEGER)`,
+		`INSERT INTO a(n) VALUES(1), (2), (3)`,
+		`INSERT INTO b VALUES(1)`,
+		`INSERT INTO skipped VALUES(1), (2)`,
+		`CREATE INDEX a_n ON a(n)`,
+		`CREATE INDEX skipped_n ON skipped(n)`,
+		`CREATE TRIGGER a_tr AFTER INSERT ON a BEGIN SELECT 1; END`,
+		`CREATE VIEW v AS SELECT n FROM a`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	cases := map[string]struct {
+		options []Option
+		expect  Stats
+	}{
+		"No Options": {
+			expect: Stats{Tables: 3, Rows: 7, Indexes: 2, Triggers: 1, Views: 1},
+		},
+		"WithExcludeTables": {
+			options: []Option{WithExcludeTables("skipped", "sqlite_sequence")},
+			expect:  Stats{Tables: 2, Rows: 4, Indexes: 1, Triggers: 1, Views: 1},
+		},
+		"WithoutData": {
+			options: []Option{WithoutData()},
+			expect:  Stats{Tables: 3, Indexes: 2, Triggers: 1, Views: 1},
+		},
+		"WithDataOnly": {
+			options: []Option{WithDataOnly(), WithTables("a")},
+			expect:  Stats{Tables: 1, Rows: 3},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			stats, err := DumpDBStats(db, &b, c.options...)
+			require.NoError(t, err)
+			assert.Equal(t, c.expect, stats)
+			assert.Equal(t, int(c.expect.Rows), strings.Count(b.String(), "INSERT INTO"))
+		})
+	}
+}
+
+func TestDumperReuse(t *testing.T) {
+	expect, err := ioutil.ReadFile("testdata/drop_if_exists.sql")
+	require.NoError(t, err)
+
+	dumper := New(WithDropIfExists(true))
+	for i := 0; i < 2; i++ {
+		var b bytes.Buffer
+		err = dumper.Dump("testdata/cars.db", &b)
+		require.NoError(t, err)
+		assertEqualIgnoreLineSeparators(t, expect, b.Bytes())
+	}
+
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	err = dumper.DumpDB(db, &b)
+	require.NoError(t, err)
+	assertEqualIgnoreLineSeparators(t, expect, b.Bytes())
+}
+
+func TestWithTransactionMode(t *testing.T) {
+	for _, mode := range []string{"DEFERRED", "IMMEDIATE", "exclusive"} {
+		t.Run(mode, func(t *testing.T) {
+			got, err := DumpString("testdata/cars.db", WithTransactionMode(mode), WithoutData())
+			require.NoError(t, err)
+			expect := "BEGIN " + strings.ToUpper(mode) + " TRANSACTION;\n" +
+				"CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n" +
+				"COMMIT;\n"
+			assert.Equal(t, expect, got)
+		})
+	}
+
+	_, err := DumpString("testdata/cars.db", WithTransactionMode("EVENTUALLY"))
+	assert.Error(t, err)
+}
+
+func TestWithTransactionStatements(t *testing.T) {
+	got, err := DumpString("testdata/cars.db", WithTransactionStatements("SAVEPOINT dump;", "RELEASE dump;"), WithoutData())
+	require.NoError(t, err)
+	expect := "SAVEPOINT dump;\n" +
+		"CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n" +
+		"RELEASE dump;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString("testdata/cars.db", WithTransactionStatements("", ""), WithoutData())
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n", got)
+
+	// the checkpoints use them too
+	got, err = DumpString("testdata/cars.db", WithTransactionStatements("SAVEPOINT dump;", "RELEASE dump;"), WithDataOnly(), WithCheckpointEvery(4))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(got, "SAVEPOINT dump;\n"))
+	assert.Equal(t, 2, strings.Count(got, "RELEASE dump;\n"))
+	assert.NotContains(t, got, "COMMIT")
+	restoreDB(t, "CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n"+got)
+}
+
+func TestWithBeforeAndAfterTable(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`CREATE TABLE b(n INTEGER)`,
+		`INSERT INTO a VALUES(1), (2)`,
+		`CREATE INDEX a_n ON a(n)`,
+	)
+
+	got, err := DumpString(dbName,
+		WithBeforeTable(func(table string) string { return "-- rows of " + table }),
+		WithAfterTable(func(table string) string {
+			if table == "b" {
+				return ""
+			}
+			return fmt.Sprintf("ANALYZE %q;\n", table)
+		}),
+	)
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE a(n INTEGER);\n" +
+		"-- rows of a\n" +
+		`INSERT INTO "a" VALUES(1);` + "\n" +
+		`INSERT INTO "a" VALUES(2);` + "\n" +
+		`ANALYZE "a";` + "\n" +
+		"CREATE TABLE b(n INTEGER);\n" +
+		"-- rows of b\n" +
+		"CREATE INDEX a_n ON a(n);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString(dbName, WithoutData(), WithBeforeTable(func(table string) string { return "-- " + table }))
+	require.NoError(t, err)
+	assert.NotContains(t, got, "--")
+}
+
+func TestWithFooter(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+	)
+
+	got, err := DumpString(dbName, WithFooter("PRAGMA optimize;"), WithFooter("VACUUM;\n"), WithFooter(""), WithForeignKeysOff())
+	require.NoError(t, err)
+	expect := "PRAGMA foreign_keys=OFF;\n" +
+		"BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(a INTEGER);\n" +
+		`INSERT INTO "t" VALUES(1);` + "\n" +
+		"COMMIT;\n" +
+		"PRAGMA foreign_keys=ON;\n" +
+		"PRAGMA optimize;\n" +
+		"VACUUM;\n"
+	assert.Equal(t, expect, got)
+	// VACUUM fails within a transaction
+	restoreDB(t, got)
+
+	got, err = DumpString(dbName, WithFooter("VACUUM"), WithExcludeTables("t"))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestWithInsertVerb(t *testing.T) {
+	for _, verb := range []string{"INSERT", "INSERT OR REPLACE", "insert or ignore", "REPLACE"} {
+		t.Run(verb, func(t *testing.T) {
+			got, err := DumpString("testdata/cars.db", WithInsertVerb(verb), WithDataOnly(), WithTransaction(false))
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(got, strings.ToUpper(verb)+` INTO "Cars" VALUES(1,'Audi',52642);`+"\n"), got)
+
+			got, err = DumpString("testdata/cars.db", WithInsertVerb(verb), WithMigration())
+			require.NoError(t, err)
+			assert.Contains(t, got, strings.ToUpper(verb)+` INTO "Cars"("Id","Name","Price") VALUES(1,'Audi',52642);`+"\n")
+		})
+	}
+
+	// the rows replace or are ignored in a database that already has them
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, b TEXT)`,
+		`INSERT INTO t VALUES(1, 'dumped')`,
+	)
+	for verb, expect := range map[string]string{"INSERT OR REPLACE": "dumped", "REPLACE": "dumped", "INSERT OR IGNORE": "kept"} {
+		got, err := DumpString(dbName, WithInsertVerb(verb), WithDataOnly())
+		require.NoError(t, err)
+		db := restoreDB(t, "CREATE TABLE t(id INTEGER PRIMARY KEY, b TEXT);\nINSERT INTO t VALUES(1, 'kept');\n")
+		require.NoError(t, RestoreDB(db, strings.NewReader(got)), verb)
+		var b string
+		require.NoError(t, db.QueryRow(`SELECT b FROM t WHERE id = 1`).Scan(&b))
+		assert.Equal(t, expect, b, verb)
+	}
+
+	_, err := DumpString("testdata/cars.db", WithInsertVerb("UPSERT"))
+	assert.Error(t, err)
+}
+
+func TestSnapshot(t *testing.T) {
+	dbName := createDB(t,
+		`PRAGMA journal_mode=WAL`,
+		`CREATE TABLE a(n INTEGER)`,
+		`CREATE TABLE b(n INTEGER)`,
+		`INSERT INTO a VALUES(1)`,
+		`INSERT INTO b VALUES(1)`,
+	)
+
+	cases := map[string]struct {
+		options []Option
+		expectB int
+	}{
+		"snapshot":        {expectB: 1},
+		"WithoutSnapshot": {options: []Option{WithoutSnapshot()}, expectB: 2},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			writer, err := sql.Open("sqlite3", dbName)
+			require.NoError(t, err)
+			defer writer.Close()
+
+			// write to b while the dump is in the middle of a
+			out := &hookWriter{trigger: `INSERT INTO "a"`, hook: func() {
+				_, err := writer.Exec(`INSERT INTO b VALUES(2)`)
+				require.NoError(t, err)
+			}}
+			err = Dump(dbName, out, append(c.options, WithDataOnly())...)
+			require.NoError(t, err)
+			assert.Equal(t, c.expectB, strings.Count(out.String(), `INSERT INTO "b"`))
+
+			_, err = writer.Exec(`DELETE FROM b WHERE n = 2`)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBlob(t *testing.T) {
+	blob := []byte{0x00, 0xff, 0xfe, 'a', 0x00, 0x80, '\''}
+
+	dbName := filepath.Join(t.TempDir(), "blob.db")
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE files(name TEXT, data BLOB)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO files VALUES(?, ?)`, "bin", blob)
+	require.NoError(t, err)
+
+	dump, err := DumpDBString(db)
+	require.NoError(t, err)
+	assert.Contains(t, dump, `INSERT INTO "files" VALUES('bin',X'00FFFE61008027');`)
+
+	restored := restoreDB(t, dump)
+	var got []byte
+	require.NoError(t, restored.QueryRow(`SELECT data FROM files`).Scan(&got))
+	assert.Equal(t, blob, got)
+}
+
+func TestWithColumnNames(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER, "odd ""name" TEXT)`,
+		`INSERT INTO t VALUES(1, 'one')`,
+	)
+
+	got, err := DumpString(dbName, WithColumnNames())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`CREATE TABLE t(id INTEGER, "odd ""name" TEXT);` + "\n" +
+		`INSERT INTO "t"("id","odd ""name") VALUES(1,'one');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	restoreDB(t, got)
+}
+
+func TestWithSchema(t *testing.T) {
+	mainName := createDB(t,
+		`CREATE TABLE main_table(n INTEGER)`,
+		`INSERT INTO main_table VALUES(1)`,
+	)
+	auxName := filepath.Join(t.TempDir(), "aux.db")
+
+	db, err := sql.Open("sqlite3", mainName)
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`ATTACH DATABASE ? AS aux`, auxName)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE aux.child(id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id));
+		CREATE TABLE aux.parent(id INTEGER PRIMARY KEY);
+		INSERT INTO aux.parent VALUES(1);
+		INSERT INTO aux.child VALUES(1, 1);
+		CREATE INDEX aux.child_parent ON child(parent_id);
+	`)
+	require.NoError(t, err)
+
+	got, err := DumpDBString(db, WithSchema("aux"), WithStableOrder(), WithDependencyOrder())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE parent(id INTEGER PRIMARY KEY);\n" +
+		`INSERT INTO "parent" VALUES(1);` + "\n" +
+		"CREATE TABLE child(id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id));\n" +
+		`INSERT INTO "child" VALUES(1,1);` + "\n" +
+		"CREATE INDEX child_parent ON child(parent_id);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithRenameSchema(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT)`,
+		`INSERT INTO t(b) VALUES('one')`,
+	)
+
+	got, err := DumpString(dbName, WithRenameSchema("archive"), WithDataOnly(), WithDropIfExists(true))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "archive"."t" VALUES(1,'one');` + "\n" +
+		`DELETE FROM "archive"."sqlite_sequence";` + "\n" +
+		`INSERT INTO "archive"."sqlite_sequence" VALUES('t',1);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString(dbName, WithRenameSchema("archive"), WithoutData(), WithDropIfExists(true))
+	require.NoError(t, err)
+	assert.Contains(t, got, `DROP TABLE IF EXISTS "archive"."t";`+"\n")
+
+	// the rows go into the attached database, not into the main one with the same table
+	db, err := sql.Open("sqlite3", createDB(t, `CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT)`))
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`ATTACH DATABASE ? AS archive`, createDB(t, `CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT)`))
+	require.NoError(t, err)
+	got, err = DumpString(dbName, WithRenameSchema("archive"), WithMigration())
+	require.NoError(t, err)
+	require.NoError(t, RestoreDB(db, strings.NewReader(got)))
+
+	var mainRows, archiveRows int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM main.t`).Scan(&mainRows))
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM archive.t`).Scan(&archiveRows))
+	assert.Equal(t, 0, mainRows)
+	assert.Equal(t, 1, archiveRows)
+}
+
+func TestWithTableTargetSchema(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(x)`,
+		`CREATE TABLE b(y)`,
+		`CREATE TABLE c(z)`,
+		`INSERT INTO a VALUES(1)`,
+		`INSERT INTO b VALUES(2)`,
+		`INSERT INTO c VALUES(3)`,
+	)
+	shard1 := createDB(t, `CREATE TABLE a(x)`)
+	shard2 := createDB(t, `CREATE TABLE b(y)`)
+
+	got, err := DumpString(dbName, WithDataOnly(),
+		WithTableTargetSchema("A", "shard1"), WithTableTargetSchema("b", "shard2"),
+		WithAttach("shard1", shard1), WithAttach("shard2", shard2),
+	)
+	require.NoError(t, err)
+	expect := "ATTACH DATABASE " + QuoteValue(shard1) + ` AS "shard1";` + "\n" +
+		"ATTACH DATABASE " + QuoteValue(shard2) + ` AS "shard2";` + "\n" +
+		"BEGIN TRANSACTION;\n" +
+		`INSERT INTO "shard1"."a" VALUES(1);` + "\n" +
+		`INSERT INTO "shard2"."b" VALUES(2);` + "\n" +
+		`INSERT INTO "c" VALUES(3);` + "\n" +
+		"COMMIT;\n" +
+		`DETACH DATABASE "shard1";` + "\n" +
+		`DETACH DATABASE "shard2";` + "\n"
+	assert.Equal(t, expect, got)
+
+	// a single script fills every database
+	target := createDB(t, `CREATE TABLE c(z)`)
+	require.NoError(t, Restore(target, strings.NewReader(got)))
+	for dbName, table := range map[string]string{shard1: "a", shard2: "b", target: "c"} {
+		db, err := sql.Open("sqlite3", dbName)
+		require.NoError(t, err)
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT count(*) FROM `+table).Scan(&n))
+		db.Close()
+		assert.Equal(t, 1, n, table)
+	}
+
+	// a dump of nothing attaches nothing
+	got, err = DumpString(dbName, WithDataOnly(), WithTables("a"), WithWhere("a", "0"), WithAttach("shard1", shard1))
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+}
+
+func TestDumpSplit(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`INSERT INTO t VALUES(1, 'one'), (2, 'two')`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+	)
+
+	var schemaOut, dataOut bytes.Buffer
+	err := DumpSplit(dbName, &schemaOut, &dataOut, WithDropIfExists(true))
+	require.NoError(t, err)
+
+	expectSchema := "DROP VIEW IF EXISTS \"v\";\n" +
+		"DROP INDEX IF EXISTS \"t_a\";\n" +
+		"DROP TABLE IF EXISTS \"t\";\n" +
+		"CREATE TABLE t(a INTEGER, b TEXT);\n" +
+		"CREATE INDEX t_a ON t(a);\n" +
+		"CREATE VIEW v AS SELECT a FROM t;\n"
+	assert.Equal(t, expectSchema, schemaOut.String())
+
+	expectData := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t" VALUES(1,'one');` + "\n" +
+		`INSERT INTO "t" VALUES(2,'two');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expectData, dataOut.String())
+
+	restored := restoreDB(t, schemaOut.String())
+	_, err = restored.Exec(dataOut.String())
+	require.NoError(t, err)
+
+	expect, err := DumpString(dbName)
+	require.NoError(t, err)
+	got, err := DumpDBString(restored)
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+}
+
+var errWriterFull = errors.New("writer is full")
+
+// limitWriter fails with errWriterFull once more than n bytes are written.
+type limitWriter struct {
+	n, written int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.n {
+		return 0, errWriterFull
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestWriteErrors(t *testing.T) {
+	cases := map[string][]Option{
+		"No Options":          nil,
+		"WithConcurrency":     {WithConcurrency(2)},
+		"WithInsertBatchSize": {WithInsertBatchSize(3)},
+	}
+
+	for name, options := range cases {
+		t.Run(name, func(t *testing.T) {
+			options = append(options, WithDropIfExists(true), WithForeignKeysOff())
+			full, err := DumpString("testdata/cars.db", options...)
+			require.NoError(t, err)
+
+			// fail at every statement in turn
+			lines := strings.SplitAfter(full, "\n")
+			for i, n := 0, 0; i < len(lines)-1; i++ {
+				err := Dump("testdata/cars.db", &limitWriter{n: n}, options...)
+				assert.True(t, errors.Is(err, errWriterFull), "expected errWriterFull at %q, got %v", lines[i], err)
+				n += len(lines[i])
+			}
+		})
+	}
+}
+
+func TestWriteErrorNamesStatement(t *testing.T) {
+	err := Dump("testdata/cars.db", &limitWriter{n: 0})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BEGIN TRANSACTION;")
+
+	err = Dump("testdata/cars.db", &limitWriter{n: 200})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `table "Cars"`)
+}
+
+func TestWithIdentifierQuote(t *testing.T) {
+	dbName := createDB(t,
+		"CREATE TABLE t(id INTEGER, \"we`ird\" TEXT)",
+		`CREATE INDEX t_id ON t(id)`,
+		`INSERT INTO t VALUES(1, 'a')`,
+	)
+
+	got, err := DumpString(dbName, WithIdentifierQuote('`'), WithColumnNames(), WithDropIfExists(true))
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"DROP INDEX IF EXISTS `t_id`;\n" +
+		"DROP TABLE IF EXISTS `t`;\n" +
+		"CREATE TABLE t(id INTEGER, \"we`ird\" TEXT);\n" +
+		"INSERT INTO `t`(`id`,`we``ird`) VALUES(1,'a');\n" +
+		"CREATE INDEX t_id ON t(id);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	restoreDB(t, got)
+
+	_, err = DumpString(dbName, WithIdentifierQuote('['))
+	assert.EqualError(t, err, `unsupported identifier quote '['`)
+}
+
+func TestAnalyzedDatabase(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`INSERT INTO t VALUES(1), (1), (2)`,
+		`ANALYZE`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`ANALYZE "sqlite_master";` + "\n" +
+		`INSERT INTO "sqlite_stat1" VALUES('t','t_a','3 2');` + "\n" +
+		"CREATE TABLE t(a INTEGER);\n" +
+		`INSERT INTO "t" VALUES(1);` + "\n" +
+		`INSERT INTO "t" VALUES(1);` + "\n" +
+		`INSERT INTO "t" VALUES(2);` + "\n" +
+		"CREATE INDEX t_a ON t(a);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	db := restoreDB(t, got)
+	var stat string
+	require.NoError(t, db.QueryRow(`SELECT group_concat("tbl" || ' ' || "idx" || ' ' || "stat", '|') FROM "sqlite_stat1"`).Scan(&stat))
+	assert.Equal(t, "t t_a 3 2", stat)
+}
+
+func TestGeneratedColumns(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, doubled INTEGER GENERATED ALWAYS AS (a * 2) STORED, b TEXT, upper TEXT GENERATED ALWAYS AS (upper(b)) VIRTUAL)`,
+		`INSERT INTO t(a, b) VALUES(1, 'x'), (2, 'y')`,
+	)
+
+	for name, opts := range map[string][]Option{
+		"default":      nil,
+		"column names": {WithColumnNames()},
+		"scanned":      {WithNullAs("NULL")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, opts...)
+			require.NoError(t, err)
+			// only the values of a and b are inserted
+			assert.Contains(t, got, "VALUES(1,'x');\n")
+			assert.Contains(t, got, "VALUES(2,'y');\n")
+
+			db := restoreDB(t, got)
+			var rows string
+			require.NoError(t, db.QueryRow(`SELECT group_concat(a || doubled || b || upper, '|') FROM t`).Scan(&rows))
+			assert.Equal(t, "12xX|24yY", rows)
+		})
+	}
+}
+
+func TestNewDumpReader(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	r := NewDumpReader(db)
+	got, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	pythonOutput, _ := ioutil.ReadFile("testdata/python.sql")
+	assertEqualIgnoreLineSeparators(t, pythonOutput, got)
+}
+
+func TestNewDumpReaderError(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	got, err := ioutil.ReadAll(NewDumpReader(db, WithTables("missing")))
+	assert.EqualError(t, err, `table "missing" doesn't exist`)
+	assert.Empty(t, got)
+}
+
+func TestNewDumpReaderClose(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	r := NewDumpReader(db)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "BEGIN", string(buf))
+
+	// closing stops the dump, which would otherwise block writing the rest
+	require.NoError(t, r.Close())
+	_, err = r.Read(buf)
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
+
+func TestSpecialCharacterNames(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE "we""ird name"("co""l umn" INTEGER PRIMARY KEY, "it's" TEXT)`,
+		`CREATE TABLE "child table"(id INTEGER, parent INTEGER REFERENCES "we""ird name"("co""l umn"))`,
+		`CREATE INDEX "we""ird index" ON "we""ird name"("it's")`,
+		`INSERT INTO "we""ird name" VALUES(1, 'a'), (2, 'b')`,
+		`INSERT INTO "child table" VALUES(1, 2)`,
+	)
+
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"all options": {
+			WithColumnNames(), WithStableOrder(), WithDependencyOrder(), WithDropIfExists(true),
+			WithWhere(`we"ird name`, `"it's" = 'b'`),
+		},
+		"scanned": {WithNullAs("NULL")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, opts...)
+			require.NoError(t, err)
+
+			db := restoreDB(t, got)
+			var rows string
+			require.NoError(t, db.QueryRow(`SELECT group_concat("co""l umn" || "it's") FROM "we""ird name"`).Scan(&rows))
+			if name == "all options" {
+				assert.Equal(t, "2b", rows)
+			} else {
+				assert.Equal(t, "1a,2b", rows)
+			}
+			var parent int
+			require.NoError(t, db.QueryRow(`SELECT parent FROM "child table"`).Scan(&parent))
+			assert.Equal(t, 2, parent)
+		})
+	}
+
+	got, err := DumpString(dbName, WithColumnNames(), WithTables(`we"ird name`), WithDataOnly())
+	require.NoError(t, err)
+	assert.Contains(t, got, `INSERT INTO "we""ird name"("co""l umn","it's") VALUES(1,'a');`)
+}
+
+func TestNULCharacterNames(t *testing.T) {
+	// only a tampered sqlite_master holds such names
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`PRAGMA writable_schema=ON`,
+		`UPDATE sqlite_master SET name = 't' || char(0) || '"; DROP TABLE x; --' WHERE name = 't'`,
+	)
+	_, err := DumpString(dbName)
+	assert.EqualError(t, err, `invalid table name "t\x00\"; DROP TABLE x; --", it contains a NUL character`)
+
+	dbName = createDB(t, `CREATE TABLE t(a INTEGER)`)
+	_, err = DumpString(dbName, WithKeysetPagination("t", "a\x00"))
+	assert.EqualError(t, err, `invalid option name "a\x00", it contains a NUL character`)
+}
+
+func TestDumpNotADatabase(t *testing.T) {
+	dbName := filepath.Join(t.TempDir(), "not.db")
+	require.NoError(t, ioutil.WriteFile(dbName, bytes.Repeat([]byte("not a database\n"), 100), 0644))
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b)
+	assert.EqualError(t, err, "cannot read database "+dbName+": file is not a database")
+	assert.Empty(t, b.String())
+}
+
+func TestWithValidateOnly(t *testing.T) {
+	db, err := sql.Open("sqlite3", "testdata/cars.db")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	stats, err := DumpDBStats(db, &b, WithValidateOnly())
+	require.NoError(t, err)
+	assert.Empty(t, b.String())
+	assert.Equal(t, Stats{Tables: 1, Rows: 8}, stats)
+
+	// the rows are read, so errors of their queries are returned
+	err = Dump("testdata/cars.db", &b, WithValidateOnly(), WithWhere("Cars", "no_such_column > 1"))
+	assert.Error(t, err)
+	assert.Empty(t, b.String())
+
+	dbName := filepath.Join(t.TempDir(), "not.db")
+	require.NoError(t, ioutil.WriteFile(dbName, bytes.Repeat([]byte("not a database\n"), 100), 0644))
+	assert.Error(t, Dump(dbName, &b, WithValidateOnly()))
+}
+
+func TestWithReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a dir#%")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	dbName := filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE t(a INTEGER); INSERT INTO t VALUES(1)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	got, err := DumpString(dbName, WithReadOnly())
+	require.NoError(t, err)
+	assert.Contains(t, got, `INSERT INTO "t" VALUES(1);`)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "test.db", files[0].Name())
+
+	// a missing file isn't created
+	missing := filepath.Join(dir, "missing.db")
+	_, err = DumpString(missing, WithReadOnly())
+	assert.True(t, errors.Is(err, ErrDatabaseNotFound), err)
+	_, err = os.Stat(missing)
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestDumpEscapedName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a dir?#%")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	dbName := filepath.Join(dir, "test.db?x=1")
+	db, err := sql.Open("sqlite3", fileDSN(dbName))
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE t(a INTEGER); INSERT INTO t VALUES(1)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	for _, opts := range [][]Option{nil, {WithReadOnly()}} {
+		got, err := DumpString(dbName, opts...)
+		require.NoError(t, err)
+		assert.Contains(t, got, `INSERT INTO "t" VALUES(1);`)
+	}
+}
+
+func TestDumpDatabaseNotFound(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	err := Dump(missing, ioutil.Discard)
+	assert.True(t, errors.Is(err, ErrDatabaseNotFound), err)
+	assert.EqualError(t, err, "database not found: "+missing)
+	_, err = os.Stat(missing)
+	assert.True(t, os.IsNotExist(err), "the database isn't created")
+}
+
+func TestDumpStatError(t *testing.T) {
+	// a path through a regular file can't be stat'ed, for a reason other than not existing
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+
+	err := Dump(filepath.Join(file, "test.db"), ioutil.Discard)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrDatabaseNotFound), err)
+	assert.Contains(t, err.Error(), "not a directory")
+}
+
+func TestKeywordNames(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE "order"("select" INTEGER PRIMARY KEY AUTOINCREMENT, "from" TEXT)`,
+		`CREATE TABLE "group"("where" INTEGER REFERENCES "order"("select"), "table" TEXT)`,
+		`CREATE TABLE "index"("key" TEXT PRIMARY KEY, "values" BLOB) WITHOUT ROWID`,
+		`CREATE INDEX "on" ON "group"("table")`,
+		`CREATE VIEW "view" AS SELECT "from" FROM "order"`,
+		`CREATE TRIGGER "trigger" AFTER INSERT ON "order" BEGIN INSERT INTO "group" VALUES(new."select", 'new'); END`,
+		`INSERT INTO "order"("from") VALUES('a'), ('b')`,
+		`INSERT INTO "index" VALUES('k', x'01')`,
+		`ANALYZE`,
+	)
+
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"all options": {
+			WithColumnNames(), WithStableOrder(), WithDependencyOrder(), WithDropIfExists(true),
+			WithWhere("order", `"from" <> ''`), WithTables("order", "group", "index"),
+		},
+		"scanned": {WithNullAs("NULL"), WithIfNotExists()},
+		"keyset":  {WithKeysetPagination("order", "select"), WithKeysetPagination("index", "key"), WithKeysetPagination("group", "where"), WithMaxRowsPerTable(1)},
+		"counted": {WithTemp(), WithSequences(), WithTableRowCountComments()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, opts...)
+			require.NoError(t, err)
+
+			db := restoreDB(t, got)
+			var rows string
+			require.NoError(t, db.QueryRow(`SELECT group_concat("select" || "from") FROM "order"`).Scan(&rows))
+			assert.Equal(t, "1a,2b", rows)
+			require.NoError(t, db.QueryRow(`SELECT group_concat("where" || "table") FROM "group"`).Scan(&rows))
+			assert.Equal(t, "1new,2new", rows)
+			require.NoError(t, db.QueryRow(`SELECT "key" || hex("values") FROM "index"`).Scan(&rows))
+			assert.Equal(t, "k01", rows)
+			require.NoError(t, db.QueryRow(`SELECT group_concat("from") FROM "view"`).Scan(&rows))
+			assert.Equal(t, "a,b", rows)
+		})
+	}
+
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	tables, err := ListTables(db, WithTables("ORDER", "index"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"index", "order"}, tables)
+}
+
+func TestColumnNamesAddedColumn(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, a TEXT, g TEXT GENERATED ALWAYS AS (upper(a)))`,
+		`INSERT INTO t(id, a) VALUES(1, 'x')`,
+		`ALTER TABLE t ADD COLUMN b TEXT DEFAULT 'b'`,
+		`ALTER TABLE t ADD COLUMN c INTEGER`,
+		`INSERT INTO t VALUES(2, 'y', 'z', 3)`,
+	)
+
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"scanned": {WithNullAs("NULL")},
+		"keyset":  {WithKeysetPagination("t", "id"), WithMaxRowsPerTable(1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, append(opts, WithColumnNames(), WithDataOnly())...)
+			require.NoError(t, err)
+			assert.Contains(t, got, `INSERT INTO "t"("id","a","b","c") VALUES(1,'x','b',NULL);`)
+
+			// the names in the order of the values align them with the columns of a table created differently
+			target := restoreDB(t, `CREATE TABLE t(c INTEGER, b TEXT, id INTEGER PRIMARY KEY, a TEXT);`)
+			require.NoError(t, RestoreDB(target, strings.NewReader(got)))
+			var rows string
+			require.NoError(t, target.QueryRow(`SELECT group_concat(id || a || b || ifnull(c, '-'), ',') FROM t`).Scan(&rows))
+			assert.Equal(t, "1xb-,2yz3", rows)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// exportedTables returns the tables whose rows are exported in another format than SQL,
+// which passes the table filters and leaves out the internal tables of SQLite.
+func (s3d *Dumper) exportedTables(ctx context.Context, db preparer) ([]schema, error) {
+	tableSchemas, err := s3d.getSchemas(ctx, db, `
+        SELECT "name", "type", "tbl_name", "sql"
+        FROM `+s3d.schemaPrefix()+`"sqlite_master"
+            WHERE "sql" NOT NULL AND
+            "type" == 'table'
+            ORDER BY "name"
+		`)
+	if err != nil {
+		return nil, err
+	}
+
+	shadows := shadowTables(tableSchemas)
+	tableSchemas, _, err = s3d.filterTables(tableSchemas, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []schema{}
+	for _, schema := range tableSchemas {
+		if !strings.HasPrefix(schema.Name, "sqlite_") && !skippedTable(schema, shadows) {
+			tables = append(tables, schema)
+		}
+	}
+	return tables, nil
+}
+
+// exportRows calls start with the column names of the table, then row with the values of
+// every row, scanned like the rows of the scan-based path, and selected by the WithWhere,
+// WithRowFilter and other options.
+func (s3d *Dumper) exportRows(ctx context.Context, db preparer, schema schema, start func(columnNames []string) error, row func(values []interface{}) error) (err error) {
+	q, columnNames, err := s3d.rowQuery(ctx, db, schema, true, false)
+	if err != nil {
+		return
+	}
+	if err = start(columnNames); err != nil {
+		return
+	}
+	filter := s3d.rowFilters[strings.ToLower(schema.Name)]
+
+	stmt, err := db.PrepareContext(ctx, q)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var rowsDumped int64
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		values, include, err := s3d.scanRowValues(rows, schema.Name, columnNames, filter)
+		if err != nil {
+			return err
+		}
+		if !include {
+			continue
+		}
+		if err = row(values); err != nil {
+			return err
+		}
+		rowsDumped++
+		if rowsDumped%progressInterval == 0 {
+			s3d.reportProgress(schema.Name, rowsDumped)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	s3d.reportProgress(schema.Name, rowsDumped)
+	return
+}
+
+// export runs fn with every exported table, after validating the options, in the snapshot
+// of the dump.
+func (s3d *Dumper) export(ctx context.Context, db *sql.DB, fn func(db preparer, schema schema) error) error {
+	if err := s3d.validate(); err != nil {
+		return err
+	}
+	return s3d.inSnapshot(ctx, db, func(db preparer) error {
+		tables, err := s3d.exportedTables(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, schema := range tables {
+			if err := fn(db, schema); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ListTables returns the names of the tables a dump of the raw sql.DB with the options would
+// write, in the order it would write them, without dumping anything. They're selected by
+// the same table filters, and leave out the same internal tables of SQLite.
+func ListTables(db *sql.DB, opts ...Option) ([]string, error) {
+	return New(opts...).ListTables(db)
+}
+
+// ListTables returns the names of the tables a dump of the raw sql.DB would write,
+// see ListTables.
+func (s3d *Dumper) ListTables(db *sql.DB) (names []string, err error) {
+	if err = s3d.validate(); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	err = s3d.inSnapshot(ctx, db, func(db preparer) error {
+		tableSchemas, _, shadows, _, err := s3d.dumpedSchemas(ctx, db)
+		if err != nil {
+			return err
+		}
+		// sqlite_sequence is dumped last, see writeSequenceTable
+		tableSchemas, sequenceTable, hasSequences := withoutSequenceTable(tableSchemas)
+		if hasSequences {
+			tableSchemas = append(tableSchemas, sequenceTable)
+		}
+
+		names = []string{}
+		for _, schema := range tableSchemas {
+			if !skippedTable(schema, shadows) {
+				names = append(names, schema.Name)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// includeTable reports whether the table passes the include and exclude filters.
+// Excludes win over includes.
+func (s3d *Dumper) includeTable(name string) bool {
+	if _, ok := s3d.excludeTables[strings.ToLower(name)]; ok || matchAny(s3d.excludeTableGlobs, name) {
+		return false
+	}
+	if len(s3d.includeTables) > 0 || len(s3d.includeTableGlobs) > 0 {
+		_, ok := s3d.includeTables[strings.ToLower(name)]
+		return ok || matchAny(s3d.includeTableGlobs, name)
+	}
+	return true
+}
+
+// matchAny reports whether the name matches any of the patterns.
+// Patterns are validated up front, so match errors are ignored.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateGlobs returns an error for the first malformed pattern.
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid table glob %q: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// filterTables drops the tables not passing the filters, along with the indexes and
+// triggers that belong to them, and the indexes, triggers and views left out by the options.
+// Returns an error naming an included table that doesn't exist in the database.
+func (s3d *Dumper) filterTables(tableSchemas, otherSchemas []schema) ([]schema, []schema, error) {
+	existing := map[tableKey]bool{}
+	for _, schema := range tableSchemas {
+		existing[keyOf(schema)] = true
+	}
+	// the table of a temp object is the temp table of its name, or else the table of the
+	// main schema, such as that of a temp trigger
+	tableExists := func(schema schema) bool {
+		name := strings.ToLower(schema.TableName)
+		return existing[tableKey{temp: schema.Temp, name: name}] || (schema.Temp && existing[tableKey{name: name}])
+	}
+	// the first missing table in name order is reported, the same on every dump
+	included := make([]string, 0, len(s3d.includeTables))
+	for name := range s3d.includeTables {
+		included = append(included, name)
+	}
+	sort.Strings(included)
+	for _, name := range included {
+		if !existing[tableKey{name: name}] && !existing[tableKey{temp: true, name: name}] {
+			return nil, nil, fmt.Errorf("table %q doesn't exist", s3d.includeTables[name])
+		}
+	}
+
+	tables := []schema{}
+	for _, schema := range tableSchemas {
+		if s3d.includeTable(schema.Name) && !s3d.withoutType("table") {
+			tables = append(tables, schema)
+		}
+	}
+
+	others := []schema{}
+	for _, schema := range otherSchemas {
+		if schema.Type != "view" && tableExists(schema) && !s3d.includeTable(schema.TableName) {
+			continue
+		}
+		if s3d.onlyTableObjects && (schema.Type == "view" || !tableExists(schema)) {
+			continue
+		}
+		if s3d.withoutType(schema.Type) {
+			continue
+		}
+		others = append(others, schema)
+	}
+
+	return tables, s3d.checkDanglingViews(tableSchemas, others), nil
+}
+
+// checkDanglingViews reports the views selecting from a table the table filters leave out,
+// which would fail once restored, through the WithWarn hook, and leaves them out along with
+// their triggers with WithSkipDanglingViews. The tables are found by referencedNames, so
+// a column named like an excluded table also counts.
+func (s3d *Dumper) checkDanglingViews(tableSchemas, otherSchemas []schema) []schema {
+	if s3d.dataOnly || (!s3d.warns() && !s3d.skipDanglingViews) {
+		return otherSchemas
+	}
+	excluded := map[string]string{}
+	for _, schema := range tableSchemas {
+		if !s3d.includeTable(schema.Name) {
+			excluded[strings.ToLower(schema.Name)] = "table"
+		}
+	}
+	if len(excluded) == 0 {
+		return otherSchemas
+	}
+
+	// the views come after the views they select from, and the triggers last, see sortOtherSchemas
+	kept := make([]schema, 0, len(otherSchemas))
+	for _, schema := range otherSchemas {
+		if schema.Type == "trigger" && excluded[strings.ToLower(schema.TableName)] == "view" {
+			continue
+		}
+		if schema.Type != "view" {
+			kept = append(kept, schema)
+			continue
+		}
+		referenced := referencedNames(schema.SQL)
+		names := make([]string, 0, len(referenced))
+		for name := range referenced {
+			if excluded[name] != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			kept = append(kept, schema)
+			continue
+		}
+		sort.Strings(names)
+		if s3d.warns() {
+			s3d.warnf("view %q selects from %s %q, which isn't dumped", schema.Name, excluded[names[0]], names[0])
+		}
+		if s3d.skipDanglingViews {
+			excluded[strings.ToLower(schema.Name)] = "view"
+		} else {
+			kept = append(kept, schema)
+		}
+	}
+	return kept
+}
+
+// withoutType reports whether the tables, indexes, triggers or views are left out of the dump.
+func (s3d *Dumper) withoutType(schemaType string) bool {
+	if len(s3d.onlyTypes) > 0 && !s3d.onlyTypes[schemaType] {
+		return true
+	}
+	switch schemaType {
+	case "index":
+		return s3d.withoutIndexes
+	case "trigger":
+		return s3d.withoutTriggers
+	case "view":
+		return s3d.withoutViews
+	}
+	return false
+}
+
+// emptyTables returns the keys of the tables without any row, which WithSkipEmptyTables
+// leaves out of the data, reading at most one row of each.
+func (s3d *Dumper) emptyTables(ctx context.Context, db preparer, tableSchemas []schema, shadows map[string]string) (map[tableKey]bool, error) {
+	empty := map[tableKey]bool{}
+	for _, schema := range tableSchemas {
+		if skippedTable(schema, shadows) {
+			continue
+		}
+		stmt, err := db.PrepareContext(ctx, `SELECT EXISTS(SELECT 1 FROM `+s3d.tablePrefix(schema)+quoteIdent(schema.Name)+`)`)
+		if err != nil {
+			return nil, err
+		}
+		var exists bool
+		err = stmt.QueryRowContext(ctx).Scan(&exists)
+		stmt.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %q: %w", schema.Name, err)
+		}
+		if !exists {
+			empty[keyOf(schema)] = true
+		}
+	}
+	return empty, nil
+}
+
+// rowLimit returns the maximum number of rows dumped of the table, of WithTableLimits or else
+// WithMaxRowsPerTable, which is less than 1 for all the rows.
+func (s3d *Dumper) rowLimit(table string) int {
+	if limit, ok := s3d.tableLimits[strings.ToLower(table)]; ok {
+		return limit
+	}
+	return s3d.maxRowsPerTable
+}
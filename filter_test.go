@@ -0,0 +1,466 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createFilterDB(t *testing.T) string {
+	return createDB(t,
+		`CREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE orders(id INTEGER PRIMARY KEY, user_id INTEGER)`,
+		`CREATE TABLE logs(id INTEGER PRIMARY KEY, msg TEXT)`,
+		`CREATE INDEX logs_msg ON logs(msg)`,
+		`INSERT INTO users VALUES(1, 'alice')`,
+		`INSERT INTO orders VALUES(1, 1)`,
+		`INSERT INTO logs VALUES(1, 'hello')`,
+	)
+}
+
+func TestWithTables(t *testing.T) {
+	dbName := createFilterDB(t)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithTables("USERS", "orders"))
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE orders(id INTEGER PRIMARY KEY, user_id INTEGER);\n" +
+		`INSERT INTO "orders" VALUES(1,1);` + "\n" +
+		"CREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT);\n" +
+		`INSERT INTO "users" VALUES(1,'alice');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, b.String())
+}
+
+func TestListTables(t *testing.T) {
+	db, err := sql.Open("sqlite3", createDB(t,
+		`CREATE TABLE users(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`CREATE TABLE orders(id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id))`,
+		`CREATE TABLE logs(id INTEGER PRIMARY KEY, msg TEXT)`,
+		`CREATE VIRTUAL TABLE docs USING fts4(body)`,
+		`INSERT INTO users(name) VALUES('alice')`,
+		`CREATE VIEW v AS SELECT name FROM users`,
+	))
+	require.NoError(t, err)
+	defer db.Close()
+
+	cases := map[string]struct {
+		options []Option
+		expect  []string
+	}{
+		"all":              {expect: []string{"docs", "logs", "orders", "users", "sqlite_sequence"}},
+		"WithTables":       {options: []Option{WithTables("USERS", "orders")}, expect: []string{"orders", "users"}},
+		"WithExcludeTable": {options: []Option{WithExcludeTables("logs", "sqlite_sequence")}, expect: []string{"docs", "orders", "users"}},
+		"WithTableGlob":    {options: []Option{WithTableGlob("*s"), WithExcludeTableGlob("sqlite_*")}, expect: []string{"docs", "logs", "orders", "users"}},
+		"dependency order": {options: []Option{WithTables("orders", "users"), WithDependencyOrder()}, expect: []string{"users", "orders"}},
+		"none":             {options: []Option{WithTableGlob("none_*")}, expect: []string{}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ListTables(db, c.options...)
+			require.NoError(t, err)
+			assert.Equal(t, c.expect, got)
+		})
+	}
+
+	_, err = ListTables(db, WithTables("missing"))
+	assert.Error(t, err)
+}
+
+func TestAllTablesExcluded(t *testing.T) {
+	dbName := createFilterDB(t)
+
+	got, err := DumpString(dbName, WithExcludeTables("users", "orders", "logs"), WithForeignKeysOff())
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = DumpString(dbName, WithTableGlob("none_*"), WithHeaderText("nothing"))
+	require.NoError(t, err)
+	assert.Equal(t, "-- nothing\n", got)
+
+	// a table without rows only starts the dump with its CREATE statement
+	got, err = DumpString(dbName, WithTables("users"), WithWhere("users", "0"), WithDataOnly())
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	got, err = DumpString(dbName, WithTables("users"), WithWhere("users", "0"))
+	require.NoError(t, err)
+	assert.Equal(t, "BEGIN TRANSACTION;\nCREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT);\nCOMMIT;\n", got)
+}
+
+func TestWithExcludeTables(t *testing.T) {
+	dbName := createFilterDB(t)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithExcludeTables("Logs"), WithDropIfExists(true))
+	require.NoError(t, err)
+
+	got := b.String()
+	assert.NotContains(t, got, "logs")
+	assert.Contains(t, got, `INSERT INTO "users"`)
+	assert.Contains(t, got, `INSERT INTO "orders"`)
+}
+
+func TestExcludeWinsOverInclude(t *testing.T) {
+	dbName := createFilterDB(t)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithTables("users", "logs"), WithExcludeTables("logs"))
+	require.NoError(t, err)
+
+	got := b.String()
+	assert.Contains(t, got, `INSERT INTO "users"`)
+	assert.NotContains(t, got, "logs")
+	assert.NotContains(t, got, "orders")
+}
+
+func TestWithTablesMissing(t *testing.T) {
+	dbName := createFilterDB(t)
+
+	var b bytes.Buffer
+	err := Dump(dbName, &b, WithTables("users", "missing"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"missing"`)
+}
+
+func TestTableGlobs(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE log_2023_01(msg TEXT)`,
+		`CREATE TABLE log_2023_02(msg TEXT)`,
+		`CREATE TABLE log_2024_01(msg TEXT)`,
+		`CREATE TABLE a_temp(msg TEXT)`,
+		`CREATE TABLE ab_temp(msg TEXT)`,
+		`CREATE TABLE users(msg TEXT)`,
+	)
+
+	cases := map[string]struct {
+		options []Option
+		expect  []string
+	}{
+		"log_*": {
+			options: []Option{WithTableGlob("log_*")},
+			expect:  []string{"log_2023_01", "log_2023_02", "log_2024_01"},
+		},
+		"?_temp": {
+			options: []Option{WithTableGlob("?_temp")},
+			expect:  []string{"a_temp"},
+		},
+		"character class": {
+			options: []Option{WithTableGlob("log_202[3]_0[2-9]")},
+			expect:  []string{"log_2023_02"},
+		},
+		"additive": {
+			options: []Option{WithTableGlob("?_temp"), WithTableGlob("users")},
+			expect:  []string{"a_temp", "users"},
+		},
+		"exclude wins": {
+			options: []Option{WithTableGlob("log_*"), WithExcludeTableGlob("log_2023_*")},
+			expect:  []string{"log_2024_01"},
+		},
+		"exclude only": {
+			options: []Option{WithExcludeTableGlob("log_*"), WithExcludeTableGlob("*_temp")},
+			expect:  []string{"users"},
+		},
+		"combined with WithTables": {
+			options: []Option{WithTables("users"), WithTableGlob("a?_temp")},
+			expect:  []string{"ab_temp", "users"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := Dump(dbName, &b, append(c.options, WithoutData(), WithTransaction(false))...)
+			require.NoError(t, err)
+
+			expect := ""
+			for _, table := range c.expect {
+				expect += "CREATE TABLE " + table + "(msg TEXT);\n"
+			}
+			assert.Equal(t, expect, b.String())
+		})
+	}
+}
+
+func TestTableGlobInvalid(t *testing.T) {
+	var b bytes.Buffer
+	err := Dump("testdata/cars.db", &b, WithTableGlob("log_[2"))
+	assert.Error(t, err)
+}
+
+func TestWithWhere(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE orders(id INTEGER PRIMARY KEY, customer_id INTEGER)`,
+		`CREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT)`,
+		`INSERT INTO orders VALUES(1, 1), (2, 2), (3, 1)`,
+		`INSERT INTO users VALUES(1, 'alice'), (2, 'bob')`,
+	)
+
+	got, err := DumpString(dbName,
+		WithDataOnly(),
+		WithWhere("Orders", "customer_id = 1"),
+		WithWhere("users", "id = 1"),
+		WithWhere("missing", "id = 1"),
+	)
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "orders" VALUES(1,1);` + "\n" +
+		`INSERT INTO "orders" VALUES(3,1);` + "\n" +
+		`INSERT INTO "users" VALUES(1,'alice');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithMaxRowsPerTable(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`CREATE TABLE b(n INTEGER)`,
+		`CREATE TABLE c(n INTEGER)`,
+		`INSERT INTO a VALUES(5), (4), (3), (2), (1)`,
+		`INSERT INTO b VALUES(1)`,
+	)
+
+	got, err := DumpString(dbName, WithMaxRowsPerTable(2), WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(got, `INSERT INTO "a"`))
+	assert.Equal(t, 1, strings.Count(got, `INSERT INTO "b"`))
+	assert.Equal(t, 0, strings.Count(got, `INSERT INTO "c"`))
+
+	// the limit comes after the condition and the order
+	got, err = DumpString(dbName, WithMaxRowsPerTable(2), WithDataOnly(), WithStableOrder(), WithWhere("a", "n < 5"), WithTables("a"))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "a" VALUES(4);` + "\n" +
+		`INSERT INTO "a" VALUES(3);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString(dbName, WithMaxRowsPerTable(0), WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 5, strings.Count(got, `INSERT INTO "a"`))
+}
+
+func TestWithTableLimits(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE logs(n INTEGER)`,
+		`CREATE TABLE users(n INTEGER)`,
+		`CREATE TABLE other(n INTEGER)`,
+		`INSERT INTO logs VALUES(1), (2), (3), (4), (5)`,
+		`INSERT INTO users VALUES(1), (2), (3), (4), (5)`,
+		`INSERT INTO other VALUES(1), (2), (3), (4), (5)`,
+	)
+
+	got, err := DumpString(dbName, WithTableLimits(map[string]int{"LOGS": 1, "users": 3}), WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(got, `INSERT INTO "logs"`))
+	assert.Equal(t, 3, strings.Count(got, `INSERT INTO "users"`))
+	assert.Equal(t, 5, strings.Count(got, `INSERT INTO "other"`))
+
+	// the tables left out of the map keep the global limit, which -1 lifts
+	got, err = DumpString(dbName, WithMaxRowsPerTable(2), WithTableLimits(map[string]int{"logs": 4, "users": -1}), WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(got, `INSERT INTO "logs"`))
+	assert.Equal(t, 5, strings.Count(got, `INSERT INTO "users"`))
+	assert.Equal(t, 2, strings.Count(got, `INSERT INTO "other"`))
+}
+
+func TestDumpTable(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`CREATE TABLE other(a INTEGER)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE INDEX other_a ON other(a)`,
+		`CREATE TRIGGER t_tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`CREATE TRIGGER v_tr INSTEAD OF INSERT ON v BEGIN SELECT 1; END`,
+		`INSERT INTO t VALUES(1, 'x')`,
+		`INSERT INTO other VALUES(2)`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	require.NoError(t, DumpTable(db, &b, "T", WithTables("other")))
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(a INTEGER, b TEXT);\n" +
+		`INSERT INTO "t" VALUES(1,'x');` + "\n" +
+		"CREATE INDEX t_a ON t(a);\n" +
+		"CREATE TRIGGER t_tr AFTER INSERT ON t BEGIN SELECT 1; END;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, b.String())
+
+	err = DumpTable(db, &b, "missing")
+	assert.EqualError(t, err, `table "missing" doesn't exist`)
+
+	// of several missing tables, the first in name order is reported
+	for i := 0; i < 10; i++ {
+		_, err = DumpDBString(db, WithTables("t", "zz", "Missing", "other"))
+		assert.EqualError(t, err, `table "Missing" doesn't exist`)
+	}
+}
+
+func TestWithoutIndexesTriggersViews(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`INSERT INTO t VALUES(1)`,
+	)
+
+	cases := map[string]struct {
+		options []Option
+		expect  []string
+	}{
+		"indexes": {
+			options: []Option{WithoutIndexes()},
+			expect:  []string{"CREATE VIEW v", "CREATE TRIGGER tr"},
+		},
+		"triggers": {
+			options: []Option{WithoutTriggers()},
+			expect:  []string{"DROP INDEX", "CREATE INDEX t_a", "CREATE VIEW v"},
+		},
+		"views": {
+			options: []Option{WithoutViews()},
+			expect:  []string{"DROP INDEX", "CREATE INDEX t_a", "CREATE TRIGGER tr"},
+		},
+		"all": {
+			options: []Option{WithoutIndexes(), WithoutTriggers(), WithoutViews()},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, append(c.options, WithDropIfExists(true))...)
+			require.NoError(t, err)
+
+			assert.Contains(t, got, "CREATE TABLE t(a INTEGER);\n")
+			assert.Contains(t, got, `INSERT INTO "t" VALUES(1);`)
+			for _, object := range []string{"DROP INDEX", "CREATE INDEX t_a", "CREATE VIEW v", "CREATE TRIGGER tr"} {
+				expected := false
+				for _, e := range c.expect {
+					expected = expected || e == object
+				}
+				assert.Equal(t, expected, strings.Contains(got, object), object)
+			}
+		})
+	}
+}
+
+func TestWithOnlyTypes(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, a INTEGER)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`CREATE VIEW w AS SELECT a FROM v`,
+		`INSERT INTO t(a) VALUES(1)`,
+	)
+
+	got, err := DumpString(dbName, WithOnlyTypes("view"), WithDropIfExists(true))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`DROP VIEW IF EXISTS "w";` + "\n" +
+		`DROP VIEW IF EXISTS "v";` + "\n" +
+		"CREATE VIEW v AS SELECT a FROM t;\n" +
+		"CREATE VIEW w AS SELECT a FROM v;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString(dbName, WithOnlyTypes("TABLE"), WithOnlyTypes("trigger"))
+	require.NoError(t, err)
+	expect = "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, a INTEGER);\n" +
+		`INSERT INTO "t" VALUES(1,1);` + "\n" +
+		`DELETE FROM "sqlite_sequence";` + "\n" +
+		`INSERT INTO "sqlite_sequence" VALUES('t',1);` + "\n" +
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	_, err = DumpString(dbName, WithOnlyTypes("views"))
+	assert.EqualError(t, err, `unknown object type "views"`)
+}
+
+func TestWithSkipEmptyTables(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(x)`,
+		`CREATE TABLE b(x)`,
+		`CREATE TABLE c(id INTEGER PRIMARY KEY AUTOINCREMENT, x)`,
+		`INSERT INTO b VALUES(1)`,
+	)
+	hook := func(table string) string { return "DELETE FROM " + quoteIdent(table) + ";" }
+
+	for _, concurrency := range []int{1, 3} {
+		got, err := DumpString(dbName, WithDataOnly(), WithSkipEmptyTables(), WithBeforeTable(hook), WithConcurrency(concurrency))
+		require.NoError(t, err)
+		expect := "BEGIN TRANSACTION;\n" +
+			`DELETE FROM "b";` + "\n" +
+			`INSERT INTO "b" VALUES(1);` + "\n" +
+			"COMMIT;\n"
+		assert.Equal(t, expect, got)
+	}
+
+	got, err := DumpString(dbName, WithSkipEmptyTables())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE a(x);\n" +
+		"CREATE TABLE b(x);\n" +
+		`INSERT INTO "b" VALUES(1);` + "\n" +
+		"CREATE TABLE c(id INTEGER PRIMARY KEY AUTOINCREMENT, x);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	got, err = DumpString(dbName, WithDataOnly(), WithSkipEmptyTables(), WithTables("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+}
+
+func TestDanglingViews(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE kept(id INTEGER, x)`,
+		`CREATE TABLE secret(id INTEGER, y)`,
+		`CREATE VIEW joined AS SELECT kept.x, secret.y FROM kept JOIN secret USING(id)`,
+		`CREATE VIEW over_joined AS SELECT x FROM joined`,
+		`CREATE VIEW plain AS SELECT x FROM kept`,
+		`CREATE TRIGGER joined_insert INSTEAD OF INSERT ON joined BEGIN INSERT INTO kept VALUES(1, new.x); END`,
+		`INSERT INTO kept VALUES(1, 'a')`,
+	)
+
+	var warnings []string
+	warn := func(msg string) { warnings = append(warnings, msg) }
+	got, err := DumpString(dbName, WithExcludeTables("secret"), WithDropIfExists(true), WithWarn(warn))
+	require.NoError(t, err)
+	assert.Equal(t, []string{`view "joined" selects from table "secret", which isn't dumped`}, warnings)
+	assert.Contains(t, got, "CREATE VIEW joined")
+
+	warnings = nil
+	got, err = DumpString(dbName, WithExcludeTables("secret"), WithDropIfExists(true), WithWarn(warn), WithSkipDanglingViews())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`view "joined" selects from table "secret", which isn't dumped`,
+		`view "over_joined" selects from view "joined", which isn't dumped`,
+	}, warnings)
+	assert.NotContains(t, got, "joined")
+	assert.Contains(t, got, "CREATE VIEW plain")
+
+	// every view of the restored database can be used
+	db := restoreDB(t, got)
+	var x string
+	require.NoError(t, db.QueryRow(`SELECT x FROM plain`).Scan(&x))
+	assert.Equal(t, "a", x)
+
+	// without excluded tables nothing is reported
+	warnings = nil
+	_, err = DumpString(dbName, WithWarn(warn), WithSkipDanglingViews())
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
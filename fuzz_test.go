@@ -0,0 +1,94 @@
+//go:build go1.18
+// +build go1.18
+
+package sqlite3dump
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statementsWriter records the statements of a dump, written one per Write.
+type statementsWriter struct {
+	statements []string
+}
+
+func (sw *statementsWriter) Write(p []byte) (int, error) {
+	sw.statements = append(sw.statements, string(p))
+	return len(p), nil
+}
+
+// FuzzNames dumps a table of adversarial table and column names, and a value, and checks that
+// every statement written is a single one, whose only semicolon outside of the string literals,
+// quoted names and comments ends it, and that the dump restores the table and its row.
+func FuzzNames(f *testing.F) {
+	for _, seed := range [][3]string{
+		{"t", "c", "v"},
+		{`"); DROP TABLE t; --`, `a"b`, `'); DROP TABLE t; --`},
+		{"x'y", "`z`", `"`},
+		{"]", "[", "]'"},
+		{"a\nb", "-- c", "\r\n"},
+		{"/*", "*/", "/*"},
+		{"日本", "\t", "emoji 😀"},
+		{"", " ", ""},
+	} {
+		f.Add(seed[0], seed[1], seed[2])
+	}
+
+	f.Fuzz(func(t *testing.T, table, column, value string) {
+		if strings.ContainsRune(table+column+value, 0) {
+			t.Skip("SQL can't create a name holding a NUL character")
+		}
+		dbName := filepath.Join(t.TempDir(), "test.db")
+		db, err := sql.Open("sqlite3", dbName)
+		require.NoError(t, err)
+		defer db.Close()
+		// such as a name starting with sqlite_, which is reserved
+		if _, err = db.Exec(`CREATE TABLE ` + quoteIdent(table) + `(` + quoteIdent(column) + ` TEXT)`); err != nil {
+			t.Skip(err)
+		}
+		_, err = db.Exec(`INSERT INTO `+quoteIdent(table)+` VALUES(?)`, value)
+		require.NoError(t, err)
+
+		for name, opts := range map[string][]Option{
+			"default":  nil,
+			"options":  {WithColumnNames(), WithDropIfExists(true), WithTableRowCountComments(), WithPerTableSavepoints()},
+			"scanned":  {WithNullAs("NULL"), WithIdentifierQuote('`'), WithStableOrder()},
+			"postgres": {WithDialect(PostgresDialect{})},
+		} {
+			sw := &statementsWriter{}
+			require.NoError(t, DumpDB(db, sw, opts...), name)
+			for _, statement := range sw.statements {
+				semicolons := 0
+				for _, token := range sqlTokens(statement) {
+					if token == ";" {
+						semicolons++
+					}
+				}
+				if strings.HasPrefix(statement, "--") {
+					assert.Equal(t, 1, strings.Count(statement, "\n"), "%s: %q", name, statement)
+				} else {
+					assert.Equal(t, 1, semicolons, "%s: %q", name, statement)
+				}
+			}
+			if name == "postgres" {
+				continue
+			}
+
+			var dump bytes.Buffer
+			for _, statement := range sw.statements {
+				dump.WriteString(statement)
+			}
+			restored := restoreDB(t, dump.String())
+			var got string
+			require.NoError(t, restored.QueryRow(`SELECT `+quoteIdent(column)+` FROM `+quoteIdent(table)).Scan(&got), name)
+			assert.Equal(t, value, got, name)
+		}
+	})
+}
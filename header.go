@@ -0,0 +1,121 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// modulePath is the path of this module, used to look up its version in the build info.
+const modulePath = "github.com/thlib/sqlite3dump"
+
+// header returns the comment lines written at the top of the dump.
+func (s3d *Dumper) header(ctx context.Context, db preparer) (string, error) {
+	var b strings.Builder
+	if s3d.headerText != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(s3d.headerText, "\n"), "\n") {
+			if !strings.HasPrefix(line, "--") {
+				line = "-- " + line
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	if !s3d.headerComment {
+		return b.String(), nil
+	}
+
+	file, err := s3d.databaseFile(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	version, err := queryString(ctx, db, `SELECT sqlite_version()`)
+	if err != nil {
+		return "", err
+	}
+	// a newline in the path would end the comment, like in a table name
+	file = strings.NewReplacer("\r", " ", "\n", " ").Replace(file)
+	fmt.Fprintf(&b, "-- sqlite3dump of %s at %s using SQLite %s\n", file, time.Now().Format(time.RFC3339), version)
+	fmt.Fprintf(&b, "-- sqlite3dump version %s\n", toolVersion())
+	return b.String(), nil
+}
+
+// rowCountComment returns the comment line written before the CREATE statement of the table
+// with WithTableRowCountComments, such as '-- table users: 4210 rows'.
+func (s3d *Dumper) rowCountComment(ctx context.Context, db preparer, schema schema) (string, error) {
+	stmt, err := db.PrepareContext(ctx, `SELECT count(*) FROM `+s3d.tablePrefix(schema)+quoteIdent(schema.Name))
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+	var count int64
+	if err = stmt.QueryRowContext(ctx).Scan(&count); err != nil {
+		return "", err
+	}
+
+	// a newline in the name would end the comment
+	name := strings.NewReplacer("\r", " ", "\n", " ").Replace(schema.Name)
+	if count == 1 {
+		return fmt.Sprintf("-- table %s: 1 row\n", name), nil
+	}
+	return fmt.Sprintf("-- table %s: %d rows\n", name, count), nil
+}
+
+// databaseFile returns the file name of the dumped schema, empty for in-memory databases.
+func (s3d *Dumper) databaseFile(ctx context.Context, db preparer) (file string, err error) {
+	stmt, err := db.PrepareContext(ctx, `PRAGMA database_list`)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			seq        int
+			name, path string
+		)
+		err = rows.Scan(&seq, &name, &path)
+		if err != nil {
+			return
+		}
+		if name == s3d.schema {
+			file = path
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// queryString returns the single value selected by the query.
+func queryString(ctx context.Context, db preparer, q string) (s string, err error) {
+	stmt, err := db.PrepareContext(ctx, q)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	err = stmt.QueryRowContext(ctx).Scan(&s)
+	return
+}
+
+// toolVersion returns the version of this module the binary was built with.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
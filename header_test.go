@@ -0,0 +1,82 @@
+package sqlite3dump
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHeaderComment(t *testing.T) {
+	dbName := createDB(t, `CREATE TABLE t(n INTEGER)`)
+
+	got, err := DumpString(dbName, WithHeaderComment(), WithHeaderText("backup of t\n-- nightly"))
+	require.NoError(t, err)
+
+	lines := strings.Split(got, "\n")
+	require.True(t, len(lines) > 5, got)
+	assert.Equal(t, "-- backup of t", lines[0])
+	assert.Equal(t, "-- nightly", lines[1])
+	assert.Regexp(t, regexp.MustCompile(`^-- sqlite3dump of `+regexp.QuoteMeta(dbName)+` at \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\S* using SQLite 3\.\d+\.\d+$`), lines[2])
+	assert.Regexp(t, regexp.MustCompile(`^-- sqlite3dump version \S+$`), lines[3])
+	assert.Equal(t, "BEGIN TRANSACTION;", lines[4])
+
+	// the comments are valid SQL
+	restoreDB(t, got)
+}
+
+func TestWithHeaderTextOnly(t *testing.T) {
+	got, err := DumpString("testdata/cars.db", WithHeaderText("custom"), WithoutData())
+	require.NoError(t, err)
+
+	expect := "-- custom\n" +
+		"BEGIN TRANSACTION;\n" +
+		"CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithUTF8BOM(t *testing.T) {
+	got, err := DumpString("testdata/cars.db", WithUTF8BOM(), WithHeaderText("custom"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xEF, 0xBB, 0xBF, '-', '-'}, []byte(got)[:5])
+
+	got, err = DumpString("testdata/cars.db", WithUTF8BOM())
+	require.NoError(t, err)
+	assert.Equal(t, "\xEF\xBB\xBFBEGIN TRANSACTION;\n", got[:len("BEGIN TRANSACTION;\n")+3])
+
+	got, err = DumpString("testdata/cars.db")
+	require.NoError(t, err)
+	assert.Equal(t, "BEGIN", got[:5])
+}
+
+func TestWithTableRowCountComments(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE "empty"(n INTEGER)`,
+		`CREATE TABLE "new`+"\n"+`line"(n INTEGER)`,
+		`INSERT INTO users(name) VALUES('alice'), ('bob'), ('carol')`,
+		`INSERT INTO "new`+"\n"+`line" VALUES(1)`,
+	)
+
+	got, err := DumpString(dbName, WithTableRowCountComments(), WithWhere("users", "name = 'bob'"))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"-- table empty: 0 rows\n" +
+		`CREATE TABLE "empty"(n INTEGER);` + "\n" +
+		"-- table new line: 1 row\n" +
+		"CREATE TABLE \"new\nline\"(n INTEGER);\n" +
+		"INSERT INTO \"new\nline\" VALUES(1);\n" +
+		"-- table users: 3 rows\n" +
+		"CREATE TABLE users(id INTEGER PRIMARY KEY, name TEXT);\n" +
+		`INSERT INTO "users" VALUES(2,'bob');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	restoreDB(t, got)
+
+	got, err = DumpString(dbName, WithTableRowCountComments(), WithMigration())
+	require.NoError(t, err)
+	assert.NotContains(t, got, "-- table")
+}
@@ -0,0 +1,80 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// DumpJSON dumps the rows of the tables of a raw sql.DB as newline-delimited JSON, one object
+// per table like {"table":"t","columns":["a","b"],"rows":[[1,"x"],[2,null]]}.
+// NULL is written as null and BLOBs as base64 strings. JSON has no number for the infinite
+// reals, they're written as the strings "Infinity" and "-Infinity", and NaN, which SQLite
+// stores as NULL but a row filter can return, as null. The rows are streamed rather than
+// held in memory, and the options selecting the tables and rows apply.
+func DumpJSON(db *sql.DB, out io.Writer, opts ...Option) error {
+	s3d := New(opts...)
+	if s3d.maxBytes > 0 {
+		out = s3d.limitBytes(out)[0]
+	}
+	return s3d.export(context.Background(), db, func(db preparer, schema schema) error {
+		rowsWritten := 0
+		start := func(columnNames []string) error {
+			table, err := json.Marshal(schema.Name)
+			if err != nil {
+				return err
+			}
+			columns, err := json.Marshal(columnNames)
+			if err != nil {
+				return err
+			}
+			return writeStatement(out, `{"table":`+string(table)+`,"columns":`+string(columns)+`,"rows":[`)
+		}
+		row := func(values []interface{}) error {
+			b, err := json.Marshal(jsonValues(values))
+			if err != nil {
+				return err
+			}
+			if rowsWritten > 0 {
+				b = append([]byte{','}, b...)
+			}
+			rowsWritten++
+			if _, err := out.Write(b); err != nil {
+				return fmt.Errorf("failed to write the rows of table %q: %w", schema.Name, err)
+			}
+			return nil
+		}
+		if err := s3d.exportRows(context.Background(), db, schema, start, row); err != nil {
+			return err
+		}
+		return writeStatement(out, "]}\n")
+	})
+}
+
+// jsonValues returns the values of a row with the non-finite floats, which json.Marshal
+// rejects, replaced as DumpJSON documents.
+func jsonValues(values []interface{}) []interface{} {
+	for i, v := range values {
+		var f float64
+		switch v := v.(type) {
+		case float64:
+			f = v
+		case float32:
+			f = float64(v)
+		default:
+			continue
+		}
+		switch {
+		case math.IsInf(f, 1):
+			values[i] = "Infinity"
+		case math.IsInf(f, -1):
+			values[i] = "-Infinity"
+		case math.IsNaN(f):
+			values[i] = nil
+		}
+	}
+	return values
+}
@@ -0,0 +1,100 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpJSON(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, price REAL, data BLOB)`,
+		`CREATE TABLE empty(a INTEGER)`,
+		`CREATE TABLE skipped(a INTEGER)`,
+		`CREATE INDEX t_name ON t(name)`,
+		`INSERT INTO t(name, price, data) VALUES('it''s "x"', 1.5, x'00ff'), (NULL, NULL, NULL)`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	require.NoError(t, DumpJSON(db, &b, WithExcludeTables("skipped")))
+
+	expect := `{"table":"empty","columns":["a"],"rows":[]}` + "\n" +
+		`{"table":"t","columns":["id","name","price","data"],"rows":[[1,"it's \"x\"",1.5,"AP8="],[2,null,null,null]]}` + "\n"
+	assert.Equal(t, expect, b.String())
+
+	type table struct {
+		Table   string
+		Columns []string
+		Rows    [][]interface{}
+	}
+	var tables []table
+	dec := json.NewDecoder(strings.NewReader(b.String()))
+	for dec.More() {
+		var tbl table
+		require.NoError(t, dec.Decode(&tbl))
+		tables = append(tables, tbl)
+	}
+	require.Len(t, tables, 2)
+	assert.Equal(t, "t", tables[1].Table)
+	require.Len(t, tables[1].Rows, 2)
+	for _, row := range tables[1].Rows {
+		assert.Len(t, row, len(tables[1].Columns))
+	}
+	assert.Nil(t, tables[1].Rows[1][1])
+}
+
+func TestDumpJSONOptions(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER, email TEXT)`,
+		`CREATE TABLE other(a INTEGER)`,
+		`INSERT INTO t VALUES(1, 'a@example.com'), (2, 'b@example.com'), (3, 'c@example.com')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	err = DumpJSON(db, &b,
+		WithTables("t"),
+		WithWhere("t", "id > 1"),
+		WithRowFilter("t", func(cols []string, vals []interface{}) (bool, []interface{}) {
+			return vals[0] != int64(3), []interface{}{vals[0], "redacted"}
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `{"table":"t","columns":["id","email"],"rows":[[2,"redacted"]]}`+"\n", b.String())
+
+	err = DumpJSON(db, &b, WithTables("missing"))
+	assert.EqualError(t, err, `table "missing" doesn't exist`)
+}
+
+func TestDumpJSONInfiniteReals(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(r REAL)`,
+		`INSERT INTO t VALUES(1e999), (-1e999), (0.5)`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var b bytes.Buffer
+	require.NoError(t, DumpJSON(db, &b))
+	assert.Equal(t, `{"table":"t","columns":["r"],"rows":[["Infinity"],["-Infinity"],[0.5]]}`+"\n", b.String())
+
+	// the NaN of a row filter
+	b.Reset()
+	nan := func(cols []string, vals []interface{}) (bool, []interface{}) {
+		return true, []interface{}{math.NaN()}
+	}
+	require.NoError(t, DumpJSON(db, &b, WithRowFilter("t", nan), WithMaxRowsPerTable(1)))
+	assert.Equal(t, `{"table":"t","columns":["r"],"rows":[[null]]}`+"\n", b.String())
+}
@@ -0,0 +1,48 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// defaultPageSize is the number of rows of a page of the keyset pagination,
+// unless WithMaxRowsPerTable or WithTableLimits sets another one.
+const defaultPageSize = 1000
+
+// pageSize returns the number of rows of a page of the keyset pagination of the table.
+func (s3d *Dumper) pageSize(table string) int {
+	if limit := s3d.rowLimit(table); limit > 0 {
+		return limit
+	}
+	return defaultPageSize
+}
+
+// keysetQueries returns the queries selecting the first page of the rows of the table, and
+// the next pages, which take the last key of the previous page, see WithKeysetPagination.
+// The rows are ordered by the key column, which is selected after the row.
+func (s3d *Dumper) keysetQueries(ctx context.Context, db preparer, schema schema, scan bool, keyColumn string) (firstPage, nextPage string, columnNames []string, err error) {
+	// the unary plus keeps the driver from converting the key to time.Time, see valueSelects
+	key := quoteIdent(keyColumn)
+	q, allColumns, columnNames, err := s3d.rowSelect(ctx, db, schema, scan, "+"+key)
+	if err != nil {
+		return
+	}
+	found := false
+	for _, c := range allColumns {
+		found = found || strings.EqualFold(c.Name, keyColumn)
+	}
+	if !found {
+		err = fmt.Errorf("key column %q of table %q doesn't exist", keyColumn, schema.Name)
+		return
+	}
+
+	firstPage, nextPage = q, q+"WHERE "
+	if condition, hasCondition := s3d.where[strings.ToLower(schema.Name)]; hasCondition {
+		firstPage += "WHERE (" + condition + ")"
+		nextPage += "(" + condition + ") AND "
+	}
+	nextPage += key + " > ?"
+	order := fmt.Sprintf(" ORDER BY %s LIMIT %d", key, s3d.pageSize(schema.Name))
+	return firstPage + order, nextPage + order, columnNames, nil
+}
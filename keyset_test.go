@@ -0,0 +1,64 @@
+package sqlite3dump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithKeysetPagination(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, code TEXT UNIQUE NOT NULL)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2500)
+		INSERT INTO t SELECT i, printf('c%05d', i) FROM n`,
+		// gaps in the keys
+		`DELETE FROM t WHERE id % 7 = 0 OR id BETWEEN 1000 AND 1300`,
+	)
+
+	expect, err := DumpString(dbName, WithStableOrder())
+	require.NoError(t, err)
+
+	cases := map[string][]Option{
+		"default page size": {WithKeysetPagination("t", "id")},
+		"small pages":       {WithKeysetPagination("T", "id"), WithMaxRowsPerTable(100)},
+		"page of one row":   {WithKeysetPagination("t", "id"), WithMaxRowsPerTable(1)},
+		"text key":          {WithKeysetPagination("t", "code"), WithMaxRowsPerTable(333)},
+		"scanned rows":      {WithKeysetPagination("t", "id"), WithMaxRowsPerTable(100), WithNullAs("NULL")},
+		"without snapshot":  {WithKeysetPagination("t", "id"), WithMaxRowsPerTable(100), WithoutSnapshot()},
+	}
+	for name, options := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, options...)
+			require.NoError(t, err)
+			assert.Equal(t, expect, got)
+		})
+	}
+}
+
+func TestWithKeysetPaginationFilters(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, n INTEGER)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50)
+		INSERT INTO t SELECT i, i % 3 FROM n`,
+	)
+
+	got, err := DumpString(dbName, WithDataOnly(), WithKeysetPagination("t", "id"), WithMaxRowsPerTable(4),
+		WithWhere("t", "n = 0 OR n = 1"))
+	require.NoError(t, err)
+	expect, err := DumpString(dbName, WithDataOnly(), WithStableOrder(), WithWhere("t", "n = 0 OR n = 1"))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+
+	// the rows left out by the row filter still count for the page, so it isn't the last one
+	got, err = DumpString(dbName, WithDataOnly(), WithKeysetPagination("t", "id"), WithMaxRowsPerTable(4),
+		WithRowFilter("t", func(cols []string, vals []interface{}) (bool, []interface{}) {
+			return vals[0].(int64) > 40, vals
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, 10, strings.Count(got, "INSERT INTO"), got)
+
+	_, err = DumpString(dbName, WithKeysetPagination("t", "missing"))
+	assert.EqualError(t, err, `key column "missing" of table "t" doesn't exist`)
+}
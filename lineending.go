@@ -0,0 +1,25 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"io"
+)
+
+// lineEndingWriter writes the statements written to it, one per Write, with the line ending
+// of WithLineEnding instead of the newline that terminates them. The newlines within
+// a statement, such as those of a multi-line CREATE statement or of a text value, are kept.
+type lineEndingWriter struct {
+	w          io.Writer
+	lineEnding string
+}
+
+func (lw *lineEndingWriter) Write(p []byte) (int, error) {
+	if !bytes.HasSuffix(p, []byte("\n")) {
+		return lw.w.Write(p)
+	}
+	statement := append(p[:len(p)-1:len(p)-1], lw.lineEnding...)
+	if _, err := lw.w.Write(statement); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,49 @@
+package sqlite3dump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLineEnding(t *testing.T) {
+	dbName := createDB(t,
+		"CREATE TABLE t(\n  id INTEGER PRIMARY KEY,\n  b TEXT\n)",
+		`INSERT INTO t VALUES(1, 'one'), (2, 'two'), (3, 'line'||char(10)||'break')`,
+		`CREATE INDEX t_b ON t(b)`,
+	)
+
+	got, err := DumpString(dbName, WithLineEnding("\r\n"), WithHeaderText("first\nsecond"), WithCheckpointEvery(2))
+	require.NoError(t, err)
+	expect := "-- first\r\n" +
+		"-- second\r\n" +
+		"BEGIN TRANSACTION;\r\n" +
+		"CREATE TABLE t(\n  id INTEGER PRIMARY KEY,\n  b TEXT\n);\r\n" +
+		`INSERT INTO "t" VALUES(1,'one');` + "\r\n" +
+		`INSERT INTO "t" VALUES(2,'two');` + "\r\n" +
+		"COMMIT;\r\n" +
+		"BEGIN TRANSACTION;\r\n" +
+		"INSERT INTO \"t\" VALUES(3,'line\nbreak');\r\n" +
+		"CREATE INDEX t_b ON t(b);\r\n" +
+		"COMMIT;\r\n"
+	assert.Equal(t, expect, got)
+	assert.True(t, strings.HasSuffix(got, ";\r\n") && !strings.HasSuffix(got, "\r\n\r\n"))
+
+	db := restoreDB(t, got)
+	var b string
+	require.NoError(t, db.QueryRow(`SELECT b FROM t WHERE id = 3`).Scan(&b))
+	assert.Equal(t, "line\nbreak", b)
+
+	// every line ends the same way, and the dump with a single one
+	for _, lineEnding := range []string{"\n", "\r\n", "\r"} {
+		got, err := DumpString("testdata/cars.db", WithLineEnding(lineEnding), WithInsertBatchSize(3))
+		require.NoError(t, err)
+		assert.Equal(t, 6, strings.Count(got, ";"+lineEnding), lineEnding)
+		assert.True(t, strings.HasSuffix(got, "COMMIT;"+lineEnding), lineEnding)
+	}
+
+	_, err = DumpString("testdata/cars.db", WithLineEnding(";\n"))
+	assert.EqualError(t, err, `unsupported line ending ";\n"`)
+}
@@ -0,0 +1,22 @@
+package sqlite3dump
+
+// logger receives the diagnostics of the dump as messages with key-value pairs, it's
+// implemented by the *slog.Logger of WithLogger, which is only available from Go 1.21.
+type logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// logDebug and logInfo log the message with the logger of WithLogger, if set.
+func (s3d *Dumper) logDebug(msg string, args ...interface{}) {
+	if s3d.logger != nil {
+		s3d.logger.Debug(msg, args...)
+	}
+}
+
+func (s3d *Dumper) logInfo(msg string, args ...interface{}) {
+	if s3d.logger != nil {
+		s3d.logger.Info(msg, args...)
+	}
+}
@@ -0,0 +1,37 @@
+package sqlite3dump
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrMaxBytesExceeded is returned when the output would grow past the size of WithMaxBytes.
+var ErrMaxBytesExceeded = errors.New("maximum dump size exceeded")
+
+// maxBytesWriter writes to w as long as the bytes written to all the writers sharing written
+// stay within max. A Write that would go past it writes nothing, so the output ends with
+// the last statement that fit.
+type maxBytesWriter struct {
+	w       io.Writer
+	written *int64
+	max     int64
+}
+
+func (mw *maxBytesWriter) Write(p []byte) (int, error) {
+	if *mw.written+int64(len(p)) > mw.max {
+		return 0, ErrMaxBytesExceeded
+	}
+	n, err := mw.w.Write(p)
+	*mw.written += int64(n)
+	return n, err
+}
+
+// limitBytes returns the writers counting the bytes written to any of them against WithMaxBytes.
+func (s3d *Dumper) limitBytes(outs ...io.Writer) []io.Writer {
+	written := new(int64)
+	limited := make([]io.Writer, len(outs))
+	for i, out := range outs {
+		limited[i] = &maxBytesWriter{w: out, written: written, max: s3d.maxBytes}
+	}
+	return limited
+}
@@ -0,0 +1,41 @@
+package sqlite3dump
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxBytes(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, x TEXT)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100) INSERT INTO t SELECT i, 'row ' || i FROM n`,
+	)
+	full, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = Dump(dbName, &b, WithMaxBytes(1000))
+	assert.True(t, errors.Is(err, ErrMaxBytesExceeded), err)
+	got := b.String()
+	assert.True(t, len(got) <= 1000, len(got))
+	// the output stops mid-table, after the last statement that fit
+	assert.True(t, strings.HasPrefix(full, got))
+	assert.True(t, strings.HasSuffix(got, ");\n"), got)
+	assert.Contains(t, got, `INSERT INTO "t" VALUES(1,'row 1');`)
+	assert.NotContains(t, got, `INSERT INTO "t" VALUES(100,'row 100');`)
+
+	// the line endings and both outputs of DumpSplit count
+	var schemaOut, dataOut strings.Builder
+	err = DumpSplit(dbName, &schemaOut, &dataOut, WithMaxBytes(1000), WithLineEnding("\r\n"), WithBufferSize(64))
+	assert.True(t, errors.Is(err, ErrMaxBytesExceeded), err)
+	assert.True(t, schemaOut.Len()+dataOut.Len() <= 1000)
+	assert.True(t, strings.HasSuffix(dataOut.String(), ");\r\n"), dataOut.String())
+
+	b.Reset()
+	require.NoError(t, Dump(dbName, &b, WithMaxBytes(int64(len(full)))))
+	assert.Equal(t, full, b.String())
+}
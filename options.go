@@ -1,27 +1,728 @@
 package sqlite3dump
 
+import (
+	"strings"
+	"time"
+)
+
 // Option is SQL dump option.
-type Option func(dumper *sqlite3dumper)
+type Option func(dumper *Dumper)
 
 // WithMigration option won't include creation tables and will include table column names.
+// It writes the INSERT statements and the other CREATE statements, but no DROP statements,
+// even WithDropIfExists.
 func WithMigration() Option {
-	return func(dumper *sqlite3dumper) {
+	return func(dumper *Dumper) {
 		dumper.migration = true
+		dumper.columnNames = true
+	}
+}
+
+// WithColumnNames option includes the table column names in the INSERT statements.
+func WithColumnNames() Option {
+	return func(dumper *Dumper) {
+		dumper.columnNames = true
+	}
+}
+
+// WithoutData option dumps the schema only, leaving out the INSERT statements for table rows.
+//
+// It can't be combined with WithDataOnly, dumping returns an error if both are set.
+func WithoutData() Option {
+	return func(dumper *Dumper) {
+		dumper.schemaOnly = true
+	}
+}
+
+// WithDataOnly option dumps the table rows only, leaving out every CREATE and DROP statement.
+//
+// It can't be combined with WithoutData, dumping returns an error if both are set.
+func WithDataOnly() Option {
+	return func(dumper *Dumper) {
+		dumper.dataOnly = true
+	}
+}
+
+// WithTables option dumps only the named tables. Names match case-insensitively and
+// dumping returns an error if one of them doesn't exist in the database.
+func WithTables(names ...string) Option {
+	return func(dumper *Dumper) {
+		if dumper.includeTables == nil {
+			dumper.includeTables = map[string]string{}
+		}
+		for _, name := range names {
+			dumper.includeTables[strings.ToLower(name)] = name
+		}
+	}
+}
+
+// WithExcludeTables option leaves the named tables out of the dump. Names match
+// case-insensitively and excludes win over WithTables.
+func WithExcludeTables(names ...string) Option {
+	return func(dumper *Dumper) {
+		if dumper.excludeTables == nil {
+			dumper.excludeTables = map[string]string{}
+		}
+		for _, name := range names {
+			dumper.excludeTables[strings.ToLower(name)] = name
+		}
+	}
+}
+
+// WithTableGlob option dumps only the tables matching the shell-style pattern, using
+// path.Match semantics. Multiple patterns are additive and combine with WithTables.
+func WithTableGlob(pattern string) Option {
+	return func(dumper *Dumper) {
+		dumper.includeTableGlobs = append(dumper.includeTableGlobs, pattern)
+	}
+}
+
+// WithExcludeTableGlob option leaves the tables matching the shell-style pattern out of
+// the dump, using path.Match semantics. Excludes win over includes.
+func WithExcludeTableGlob(pattern string) Option {
+	return func(dumper *Dumper) {
+		dumper.excludeTableGlobs = append(dumper.excludeTableGlobs, pattern)
+	}
+}
+
+// WithInsertBatchSize option combines up to n rows into a single multi-row INSERT statement.
+//
+// The default of 1 writes one INSERT statement per row, smaller values are treated as 1.
+func WithInsertBatchSize(n int) Option {
+	return func(dumper *Dumper) {
+		if n < 1 {
+			n = 1
+		}
+		dumper.insertBatchSize = n
 	}
 }
 
-// WithDropIfExists option drops existing table or index if it already exists.
+// WithWhere option dumps only the rows of the table matching the condition, which is
+// appended verbatim as a WHERE clause to the query selecting the rows. Table names match
+// case-insensitively, a later condition for the same table replaces the earlier one and
+// conditions for tables that aren't dumped are ignored.
+//
+// The condition isn't escaped in any way, the caller is responsible for not passing
+// untrusted input to it as that would allow SQL injection.
+func WithWhere(table, condition string) Option {
+	return func(dumper *Dumper) {
+		if dumper.where == nil {
+			dumper.where = map[string]string{}
+		}
+		dumper.where[strings.ToLower(table)] = condition
+	}
+}
+
+// WithProgress option calls fn every 1000 rows dumped and once at the end of each table
+// with its final row count. It's called synchronously from the dumping goroutine.
+func WithProgress(fn func(table string, rowsDumped int64)) Option {
+	return func(dumper *Dumper) {
+		dumper.progress = fn
+	}
+}
+
+// WithStableOrder option dumps the rows of each table ordered by rowid, or by primary key
+// for WITHOUT ROWID tables, so dumps of an unchanged database are identical.
+func WithStableOrder() Option {
+	return func(dumper *Dumper) {
+		dumper.stableOrder = true
+	}
+}
+
+// WithDropIfExists option drops the existing tables, indexes, views and triggers of the dump
+// before creating them. They're dropped triggers first, then views, indexes, and the tables
+// referencing others by foreign keys before the tables they reference, so the drops don't
+// violate the foreign keys of a database that enforces them.
+// It's ignored by WithMigration and WithDataOnly, which don't create the tables.
 func WithDropIfExists(dropIfExists bool) Option {
-	return func(dumper *sqlite3dumper) {
+	return func(dumper *Dumper) {
 		dumper.dropIfExists = dropIfExists
 	}
 }
 
+// WithForeignKeysOff option disables foreign key enforcement while the dump is restored.
+//
+// Adds 'PRAGMA foreign_keys=OFF' at start and 'PRAGMA foreign_keys=ON' at the end.
+// The pragmas go outside of the transaction since SQLite ignores them inside one.
+func WithForeignKeysOff() Option {
+	return func(dumper *Dumper) {
+		dumper.foreignKeysOff = true
+	}
+}
+
+// WithDependencyOrder option dumps tables referenced by foreign keys before the tables
+// referencing them, instead of in alphabetical order.
+//
+// If the foreign keys form a cycle the alphabetical order is kept and the dump is wrapped
+// in 'PRAGMA foreign_keys=OFF' as with WithForeignKeysOff, no error is returned.
+func WithDependencyOrder() Option {
+	return func(dumper *Dumper) {
+		dumper.dependencyOrder = true
+	}
+}
+
 // WithTransaction wraps query with transaction.
 //
 // Adds 'BEGIN TRANSACTION' at start and 'COMMIT' at the end.
 func WithTransaction(addTransaction bool) Option {
-	return func(dumper *sqlite3dumper) {
+	return func(dumper *Dumper) {
 		dumper.wrapWithTransaction = addTransaction
 	}
 }
+
+// WithTransactionMode sets the type of the transaction the dump is wrapped with,
+// one of "DEFERRED", "IMMEDIATE" or "EXCLUSIVE". Dumping returns an error for other modes.
+//
+// Adds 'BEGIN <mode> TRANSACTION' at start instead of 'BEGIN TRANSACTION'.
+func WithTransactionMode(mode string) Option {
+	return func(dumper *Dumper) {
+		dumper.transactionMode = strings.ToUpper(mode)
+	}
+}
+
+// WithTransactionStatements option sets the statements the dump is wrapped with instead of
+// 'BEGIN TRANSACTION;' and 'COMMIT;', for engines that want other ones, such as
+// "SAVEPOINT dump;" and "RELEASE dump;". They are written as given, followed by a newline.
+// With two empty statements the dump isn't wrapped, like with WithTransaction(false).
+func WithTransactionStatements(begin, commit string) Option {
+	return func(dumper *Dumper) {
+		dumper.customTransaction = true
+		dumper.beginText = begin
+		dumper.commitText = commit
+		dumper.wrapWithTransaction = begin != "" || commit != ""
+	}
+}
+
+// WithoutSnapshot option runs every query of the dump on its own instead of in a single
+// read transaction. The dump of a database written to meanwhile may then be inconsistent.
+func WithoutSnapshot() Option {
+	return func(dumper *Dumper) {
+		dumper.withoutSnapshot = true
+	}
+}
+
+// WithSchema option dumps the tables of the named schema, such as an attached database,
+// instead of "main". The INSERT statements still target unqualified table names.
+//
+// ATTACH DATABASE only applies to the connection it's run on, so the *sql.DB should be
+// limited to a single connection with SetMaxOpenConns(1).
+func WithSchema(schemaName string) Option {
+	return func(dumper *Dumper) {
+		dumper.schema = schemaName
+	}
+}
+
+// WithIfNotExists option rewrites the CREATE statements of tables, indexes, views and
+// triggers to CREATE ... IF NOT EXISTS, so the dump can be loaded into a database that
+// already has some of them.
+func WithIfNotExists() Option {
+	return func(dumper *Dumper) {
+		dumper.ifNotExists = true
+	}
+}
+
+// WithConcurrency option dumps the rows of up to n tables in parallel, buffering each table
+// in memory until it's written out in the usual order.
+//
+// The workers read from the connection pool of the *sql.DB, so the dump isn't run in a
+// single read transaction as if WithoutSnapshot was set.
+func WithConcurrency(n int) Option {
+	return func(dumper *Dumper) {
+		dumper.concurrency = n
+	}
+}
+
+// WithHeaderComment option starts the dump with comment lines recording the database file,
+// the time of the dump, the SQLite version and the version of this package.
+func WithHeaderComment() Option {
+	return func(dumper *Dumper) {
+		dumper.headerComment = true
+	}
+}
+
+// WithHeaderText option starts the dump with the text, written before the lines of
+// WithHeaderComment. Lines not starting with "--" are turned into comments.
+func WithHeaderText(s string) Option {
+	return func(dumper *Dumper) {
+		dumper.headerText = s
+	}
+}
+
+// WithSequences option writes the rows of sqlite_sequence after the rows of all the other
+// tables, as INSERT statements naming its columns, so the AUTOINCREMENT counters of the
+// restored database are those of the dumped one, whatever the order of the tables.
+func WithSequences() Option {
+	return func(dumper *Dumper) {
+		dumper.sequences = true
+	}
+}
+
+// WithCheckpointEvery option commits the transaction wrapping the dump and begins a new one
+// after every n INSERT statements, so restoring a huge dump doesn't run as a single transaction.
+// It has no effect with WithTransaction(false), and n < 1 disables it.
+func WithCheckpointEvery(n int) Option {
+	return func(dumper *Dumper) {
+		dumper.checkpointEvery = n
+	}
+}
+
+// WithIdentifierQuote option sets the quote, '"' by default or '`', around the table and
+// column names of the INSERT, DELETE and DROP statements the package generates.
+// The CREATE statements are written verbatim from sqlite_master, so they keep the quotes
+// they were created with.
+func WithIdentifierQuote(q rune) Option {
+	return func(dumper *Dumper) {
+		dumper.identifierQuote = q
+	}
+}
+
+// WithNullAs option writes s instead of NULL for the NULL values of the rows, such as an empty
+// string literal. The rows are then formatted in Go, which is slower. With DumpCSV, s is the
+// NULL field.
+func WithNullAs(s string) Option {
+	return func(dumper *Dumper) {
+		dumper.nullAs = &s
+	}
+}
+
+// WithMaxRowsPerTable option dumps at most n rows of every table, for a sample of the database.
+// The rows are the first ones of the WithWhere condition and the WithStableOrder order, if set.
+// n < 1 dumps all the rows, which is the default. WithTableLimits sets the limits of tables.
+func WithMaxRowsPerTable(n int) Option {
+	return func(dumper *Dumper) {
+		dumper.maxRowsPerTable = n
+	}
+}
+
+// WithUTF8BOM option starts the dump with the UTF-8 byte order mark, which some Windows
+// tools expect. With DumpSplit it starts the data.
+func WithUTF8BOM() Option {
+	return func(dumper *Dumper) {
+		dumper.utf8BOM = true
+	}
+}
+
+// WithoutIndexes option leaves the indexes out of the dump, along with their DROP statements,
+// for example to load the rows faster and create the indexes afterwards.
+func WithoutIndexes() Option {
+	return func(dumper *Dumper) {
+		dumper.withoutIndexes = true
+	}
+}
+
+// WithoutTriggers option leaves the triggers out of the dump.
+func WithoutTriggers() Option {
+	return func(dumper *Dumper) {
+		dumper.withoutTriggers = true
+	}
+}
+
+// WithoutViews option leaves the views out of the dump.
+func WithoutViews() Option {
+	return func(dumper *Dumper) {
+		dumper.withoutViews = true
+	}
+}
+
+// WithReadOnly option opens the database file read-only when dumping it by name, so the dump
+// can't create or change any file, such as a journal, next to the source database.
+// A database in WAL mode can only be opened read-only if its -shm file exists or can be created.
+func WithReadOnly() Option {
+	return func(dumper *Dumper) {
+		dumper.readOnly = true
+	}
+}
+
+// WithRowFilter option passes every row of the table to fn, with the column names and the
+// values, which are nil, int64, float64, string or []byte. The row is dumped if fn returns
+// include, with the values it returns in the same order, for example to scrub personal data.
+// Table names match case-insensitively and a later filter for the same table replaces the
+// earlier one.
+//
+// The rows of the table are then scanned and formatted in Go rather than by SQLite's quote()
+// in the row query, which is slower.
+func WithRowFilter(table string, fn func(cols []string, vals []interface{}) (include bool, out []interface{})) Option {
+	return func(dumper *Dumper) {
+		if dumper.rowFilters == nil {
+			dumper.rowFilters = map[string]rowFilter{}
+		}
+		dumper.rowFilters[strings.ToLower(table)] = fn
+	}
+}
+
+// WithTimeColumn option dumps the Unix timestamps of the column as text, formatted by
+// datetime(column, 'unixepoch') like 2006-01-02 15:04:05 in UTC, for a readable export.
+// Table and column names match case-insensitively, and the option can be repeated
+// for several columns.
+func WithTimeColumn(table, column string) Option {
+	return func(dumper *Dumper) {
+		if dumper.timeColumns == nil {
+			dumper.timeColumns = map[string]map[string]bool{}
+		}
+		table = strings.ToLower(table)
+		if dumper.timeColumns[table] == nil {
+			dumper.timeColumns[table] = map[string]bool{}
+		}
+		dumper.timeColumns[table][strings.ToLower(column)] = true
+	}
+}
+
+// WithInsertVerb option sets the statement the rows are inserted with, one of "INSERT",
+// "INSERT OR REPLACE", "INSERT OR IGNORE" or "REPLACE", to load a dump into a database
+// that already has some of the rows. Dumping returns an error for other verbs.
+//
+// Adds '<verb> INTO' instead of 'INSERT INTO', also in migrations.
+func WithInsertVerb(verb string) Option {
+	return func(dumper *Dumper) {
+		dumper.insertVerb = strings.ToUpper(verb)
+	}
+}
+
+// WithTemp option also dumps the temporary tables, their rows and the other objects of the
+// temp schema, with CREATE TEMP statements, for debugging the state of a session.
+//
+// Temporary objects only exist on the connection that created them, so this only makes sense
+// with DumpDB on a *sql.DB that has a single connection, see sql.DB.SetMaxOpenConns(1).
+// It can't be combined with WithConcurrency, dumping returns an error if both are set.
+func WithTemp() Option {
+	return func(dumper *Dumper) {
+		dumper.temp = true
+	}
+}
+
+// WithRenameSchema option qualifies the tables the DROP, INSERT and DELETE statements of the
+// dump target with the schema name, to load the dump into an attached database, such as
+// 'INSERT INTO "archive"."t" VALUES(...)'.
+//
+// The CREATE statements are written as they are stored, without a schema name, so they still
+// create the objects in the main database. Use it with WithDataOnly, or with WithMigration,
+// when the schema already exists in the target database.
+func WithRenameSchema(to string) Option {
+	return func(dumper *Dumper) {
+		dumper.renameSchema = to
+	}
+}
+
+// WithWarn option calls fn with diagnostics about the data that may not restore as expected,
+// such as columns with a declared type like STRING, which SQLite gives NUMERIC affinity
+// so '0123' is stored as 123. They aren't errors, the dump is written the same.
+// It's never called concurrently, and a nil fn disables it.
+func WithWarn(fn func(msg string)) Option {
+	return func(dumper *Dumper) {
+		dumper.warn = fn
+	}
+}
+
+// WithValidateOnly option runs the whole dump, reading the schemas and every row, but
+// discards the output, to check that a database can be dumped without writing it anywhere.
+// Dumping returns the first error encountered, and the Stats of DumpDB are still counted.
+func WithValidateOnly() Option {
+	return func(dumper *Dumper) {
+		dumper.validateOnly = true
+	}
+}
+
+// WithKeysetPagination option dumps the rows of the table in pages, each selected with
+// 'WHERE key > <last key>' on the unique, not NULL key column. The pages have 1000 rows, or
+// the row limit of the table, which then no longer limits it.
+func WithKeysetPagination(table, keyColumn string) Option {
+	return func(dumper *Dumper) {
+		if dumper.keysetColumns == nil {
+			dumper.keysetColumns = map[string]string{}
+		}
+		dumper.keysetColumns[strings.ToLower(table)] = keyColumn
+	}
+}
+
+// WithLineEnding option terminates the statements of the dump with the line ending, "\n",
+// which is the default, "\r\n" or "\r". The newlines within the statements, such as those
+// of multi-line CREATE statements or of text values, are kept as they are.
+// The dump ends with a single line ending, after its last statement.
+func WithLineEnding(s string) Option {
+	return func(dumper *Dumper) {
+		dumper.lineEnding = s
+	}
+}
+
+// WithBeforeTable option writes the SQL fn returns for each table, such as 'DELETE FROM "t";',
+// before the INSERT statements of its rows, and after its CREATE statement. It's written
+// as it is, followed by a newline if it doesn't end with one, and nothing is written for
+// an empty string. Tables without rows get it too, but not sqlite_sequence, nor the tables
+// of a dump WithoutData.
+func WithBeforeTable(fn func(table string) string) Option {
+	return func(dumper *Dumper) {
+		dumper.beforeTable = fn
+	}
+}
+
+// WithAfterTable option writes the SQL fn returns for each table, such as 'ANALYZE "t";',
+// after the INSERT statements of its rows, like WithBeforeTable, and before the CREATE
+// statement of the next table.
+func WithAfterTable(fn func(table string) string) Option {
+	return func(dumper *Dumper) {
+		dumper.afterTable = fn
+	}
+}
+
+// WithBufferSize option buffers the output of the dump in n bytes, which makes fewer and larger
+// writes than the default of a write per statement, for unbuffered writers such as files or
+// network connections. What's left in the buffer is written once the dump is done, and
+// its write error returned. A write error may then name a statement written before the
+// one that failed. n < 1 doesn't buffer, which is the default.
+func WithBufferSize(n int) Option {
+	return func(dumper *Dumper) {
+		dumper.bufferSize = n
+	}
+}
+
+// WithTableRowCountComments option writes a comment line with the number of rows of each table
+// before its CREATE statement, such as '-- table users: 4210 rows', for documentation.
+// It counts all the rows of the table, whatever the WithWhere condition, with a query per table.
+func WithTableRowCountComments() Option {
+	return func(dumper *Dumper) {
+		dumper.rowCountComments = true
+	}
+}
+
+// WithOnlyTypes option only dumps the objects of the types, any of "table", "index", "trigger"
+// and "view", such as only the views of the database for a review. Without "table" no rows
+// are dumped either. Dumping returns an error for other types. The types add up with
+// repeated options, and WithoutIndexes, WithoutTriggers and WithoutViews still apply.
+func WithOnlyTypes(types ...string) Option {
+	return func(dumper *Dumper) {
+		if dumper.onlyTypes == nil {
+			dumper.onlyTypes = map[string]bool{}
+		}
+		for _, schemaType := range types {
+			dumper.onlyTypes[strings.ToLower(schemaType)] = true
+		}
+	}
+}
+
+// WithPragmaState option writes the user_version and application_id of the database as
+// 'PRAGMA user_version = N;' statements before the transaction, leaving out those that are
+// zero, or only those named in pragmas. Dumping returns an error for other pragmas.
+func WithPragmaState(pragmas ...string) Option {
+	return func(dumper *Dumper) {
+		dumper.pragmaState = true
+		for _, pragma := range pragmas {
+			dumper.pragmaStateNames = append(dumper.pragmaStateNames, strings.ToLower(pragma))
+		}
+	}
+}
+
+// WithBusyRetry option retries a query that fails with SQLITE_BUSY "database is locked",
+// up to attempts times in all, waiting for delay in between, as long as none of its rows
+// were written.
+func WithBusyRetry(attempts int, delay time.Duration) Option {
+	return func(dumper *Dumper) {
+		dumper.busyAttempts = attempts
+		dumper.busyDelay = delay
+	}
+}
+
+// WithFooter option writes the statement at the end of the dump, after the COMMIT of its
+// transaction, such as "PRAGMA optimize;" or "VACUUM;" to run once the dump is restored.
+// It's written as it is, followed by a newline if it doesn't end with one, and repeated
+// options write their statements in order. A dump without any other statement has none.
+func WithFooter(sql string) Option {
+	return func(dumper *Dumper) {
+		if sql != "" {
+			dumper.footers = append(dumper.footers, sql)
+		}
+	}
+}
+
+// WithColumnTransform option replaces the values of the column with those fn returns, such as
+// hashes of the emails or NULL for the identity numbers, for an anonymized dump. It's called
+// with the values as scanned from the database, like the values of WithRowFilter, after the
+// row filter of the table, and can return the same types. Table and column names match
+// case-insensitively, and the option can be repeated for several columns.
+//
+// The rows of the table are then scanned and formatted in Go rather than by SQLite's quote()
+// in the row query, which is slower.
+func WithColumnTransform(table, column string, fn func(v interface{}) interface{}) Option {
+	return func(dumper *Dumper) {
+		if dumper.columnTransforms == nil {
+			dumper.columnTransforms = map[string]map[string]func(v interface{}) interface{}{}
+		}
+		table = strings.ToLower(table)
+		if dumper.columnTransforms[table] == nil {
+			dumper.columnTransforms[table] = map[string]func(v interface{}) interface{}{}
+		}
+		dumper.columnTransforms[table][strings.ToLower(column)] = fn
+	}
+}
+
+// WithSkipEmptyTables option leaves the tables without any row out of the data of the dump:
+// nothing is written for them between their CREATE TABLE statement and the next table's,
+// not even the statements of WithBeforeTable and WithAfterTable. With WithDataOnly, empty
+// tables don't appear in the dump at all. Their schema is still dumped.
+func WithSkipEmptyTables() Option {
+	return func(dumper *Dumper) {
+		dumper.skipEmptyTables = true
+	}
+}
+
+// WithSkipDanglingViews option leaves out the views selecting from a table the table filters
+// leave out, and the triggers of those views, since they would fail once restored, as well
+// as the views selecting from them. Such views are reported through the WithWarn hook either
+// way. The tables a view selects from are found by a scan of its SQL, so a view with a column
+// named like an excluded table is left out too.
+func WithSkipDanglingViews() Option {
+	return func(dumper *Dumper) {
+		dumper.skipDanglingViews = true
+	}
+}
+
+// WithMaxBytes option limits the dump to n bytes, for dumps served to clients that shouldn't
+// get more. Dumping returns an error wrapping ErrMaxBytesExceeded, and stops, when the next
+// statement doesn't fit, so the output holds the statements before it, which can be
+// kept or discarded. With DumpSplit, n is the size of both outputs together. It also
+// applies to DumpJSON. n < 1 doesn't limit the size, which is the default.
+func WithMaxBytes(n int64) Option {
+	return func(dumper *Dumper) {
+		dumper.maxBytes = n
+	}
+}
+
+// WithOrderBy option dumps the rows of the table ordered by the expression, which is appended
+// verbatim as an ORDER BY clause to the query selecting the rows, such as
+// "name COLLATE NOCASE, id", instead of the order of WithStableOrder. The rows of
+// WithMaxRowsPerTable are then the first ones of that order. Table names match
+// case-insensitively and a later expression for the same table replaces the earlier one.
+// It can't be combined with WithKeysetPagination for the same table, whose rows are ordered
+// by their key, dumping returns an error if both are set.
+//
+// The expression isn't escaped in any way, like the condition of WithWhere.
+func WithOrderBy(table, expr string) Option {
+	return func(dumper *Dumper) {
+		if dumper.orderBy == nil {
+			dumper.orderBy = map[string]string{}
+		}
+		dumper.orderBy[strings.ToLower(table)] = expr
+	}
+}
+
+// WithTableTargetSchema option qualifies the table the DROP, INSERT and DELETE statements of
+// the table target with the schema name, like WithRenameSchema does for every table, such
+// as 'INSERT INTO "shard2"."orders" VALUES(...)', to populate several databases attached with
+// WithAttach from a single dump. Table names match case-insensitively and the schema
+// replaces the one of WithRenameSchema for the table.
+//
+// The CREATE statements are still written without a schema name, see WithRenameSchema.
+func WithTableTargetSchema(table, schema string) Option {
+	return func(dumper *Dumper) {
+		if dumper.tableTargetSchemas == nil {
+			dumper.tableTargetSchemas = map[string]string{}
+		}
+		dumper.tableTargetSchemas[strings.ToLower(table)] = schema
+	}
+}
+
+// WithAttach option writes 'ATTACH DATABASE <path> AS <name>;' before the transaction of the
+// dump, and 'DETACH DATABASE <name>;' at its end, after the statements of WithFooter, so the
+// statements of WithTableTargetSchema and WithRenameSchema can target the database file.
+// The path is written as a string literal and resolved by the restoring process. Repeated
+// options attach their databases in order, and a dump without any other statement has none.
+func WithAttach(name, path string) Option {
+	return func(dumper *Dumper) {
+		dumper.attachments = append(dumper.attachments, attachment{name: name, path: path})
+	}
+}
+
+// WithResumeFrom option resumes an interrupted dump with the same options: it skips the
+// tables before the table and its CREATE statement, and dumps its rows after afterRowid in
+// rowid order. The table and afterRowid are the ResumeTable and ResumeAfterRowid of the Stats
+// of the failed dump. Dumping returns an error for a WITHOUT ROWID table, or with
+// WithKeysetPagination or WithOrderBy for the table.
+func WithResumeFrom(table string, afterRowid int64) Option {
+	return func(dumper *Dumper) {
+		dumper.resumeTable = table
+		dumper.resumeAfter = afterRowid
+	}
+}
+
+// WithCreateRewriter option writes the statement fn returns instead of the CREATE statement
+// of each table, index, trigger and view, such as one with another column type for
+// another database engine. fn is called with the type of the object ("table", "index",
+// "trigger" or "view"), its name and its statement as it would be written, after the
+// changes of WithIfNotExists and WithTemp, without the semicolon ending it.
+// The statement returned is written as is, ended with a semicolon: it's up to fn to return
+// valid SQL, which restores the rows and the other objects of the dump. A nil fn doesn't
+// rewrite.
+func WithCreateRewriter(fn func(objType, name, sql string) string) Option {
+	return func(dumper *Dumper) {
+		dumper.createRewriter = fn
+	}
+}
+
+// WithQueryOnly option dumps a raw sql.DB on a single connection of its pool with PRAGMA
+// query_only set, so the dump can't write to the database, and then resets the pragma.
+// With WithConcurrency, the workers share the connection rather than reading from the pool.
+// A database dumped by name is always opened with the pragma set.
+func WithQueryOnly() Option {
+	return func(dumper *Dumper) {
+		dumper.queryOnlyConn = true
+	}
+}
+
+// WithSchemaQuery option selects the objects to dump, in its order, with the query returning
+// the name, type and sql columns of sqlite_master, optionally with tbl_name before sql,
+// such as 'SELECT name, type, sql FROM sqlite_master ORDER BY rowid'.
+func WithSchemaQuery(q string) Option {
+	return func(dumper *Dumper) {
+		dumper.schemaQuery = q
+	}
+}
+
+// WithDialect option writes the dump for another database engine with the dialect, such
+// as PostgresDialect: its names, CREATE statements and values, leaving out the statements
+// of SQLite only, the foreign_keys pragma and the rows of sqlite_sequence and of the
+// statistics tables. WithIdentifierQuote doesn't apply, the dialect quotes the names.
+// The statements of the other options are written as for SQLite, dumping returns an error
+// for an insert verb other than INSERT.
+//
+// It's a translation of the common cases, which the dialect documents, not of all of SQL:
+// the dump is to be checked.
+func WithDialect(d Dialect) Option {
+	return func(dumper *Dumper) {
+		dumper.dialect = d
+	}
+}
+
+// WithTableLimits option dumps at most the number of rows the map holds for each table, like
+// WithMaxRowsPerTable does for every table, whose limit the other tables keep. A number < 1
+// dumps all the rows of the table, such as -1 for a table to dump whole despite
+// WithMaxRowsPerTable. Table names match case-insensitively and the limits add to those of
+// an earlier WithTableLimits, replacing those of the same tables.
+func WithTableLimits(limits map[string]int) Option {
+	return func(dumper *Dumper) {
+		if dumper.tableLimits == nil {
+			dumper.tableLimits = map[string]int{}
+		}
+		for table, n := range limits {
+			dumper.tableLimits[strings.ToLower(table)] = n
+		}
+	}
+}
+
+// WithPerTableSavepoints option wraps the statements of each table in a savepoint named after
+// it, 'SAVEPOINT "t";' to 'RELEASE "t";', so a failed restore can roll back a single table.
+func WithPerTableSavepoints() Option {
+	return func(dumper *Dumper) {
+		dumper.perTableSavepoints = true
+	}
+}
+
+// WithExplicitSequenceTable option creates sqlite_sequence, if the database has it, with
+// 'CREATE TABLE IF NOT EXISTS sqlite_sequence(name,seq);' before the other tables, rather than
+// leaving SQLite to create it with the first AUTOINCREMENT table, for a restored schema
+// that is the same as the dumped one. As SQLite reserves the name, the statement is written
+// between 'PRAGMA writable_schema=ON;' and 'PRAGMA writable_schema=OFF;'. The rows of
+// sqlite_sequence are dumped as without the option, or as with WithSequences.
+func WithExplicitSequenceTable() Option {
+	return func(dumper *Dumper) {
+		dumper.explicitSequences = true
+	}
+}
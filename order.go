@@ -0,0 +1,264 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// withoutRowidRegexp matches the WITHOUT ROWID table option after the column definitions.
+var withoutRowidRegexp = regexp.MustCompile(`(?is)\)[^)]*\bWITHOUT\s+ROWID\b[^)]*$`)
+
+// stableOrder returns the ORDER BY expression dumping the rows of the table in a stable order:
+// the rowid, the primary key of a WITHOUT ROWID table, or all columns when there's neither.
+func stableOrder(schema schema, columns []column) string {
+	if !withoutRowidRegexp.MatchString(schema.SQL) {
+		// the rowid can be shadowed by a column of the same name
+		taken := map[string]bool{}
+		for _, c := range columns {
+			taken[strings.ToLower(c.Name)] = true
+		}
+		for _, alias := range []string{"rowid", "_rowid_", "oid"} {
+			if !taken[alias] {
+				return alias
+			}
+		}
+	}
+
+	pk := make([]string, 0, len(columns))
+	for position := 1; position <= len(columns); position++ {
+		for _, c := range columns {
+			if c.PK == position {
+				pk = append(pk, quoteIdent(c.Name))
+			}
+		}
+	}
+	if len(pk) > 0 {
+		return strings.Join(pk, ",")
+	}
+
+	all := make([]string, len(columns))
+	for i, c := range columns {
+		all[i] = quoteIdent(c.Name)
+	}
+	return strings.Join(all, ",")
+}
+
+// sortByDependencies orders the tables so that tables referenced by foreign keys come
+// before the tables referencing them, keeping the alphabetical order otherwise.
+// If the foreign keys form a cycle the tables are returned unchanged and cycle is true.
+func (s3d *Dumper) sortByDependencies(ctx context.Context, db preparer, tableSchemas []schema) (sorted []schema, cycle bool, err error) {
+	parents, err := s3d.foreignKeyParents(ctx, db, tableSchemas)
+	if err != nil {
+		return
+	}
+	sorted, cycle = sortTables(tableSchemas, parents)
+	return
+}
+
+// foreignKeyParents maps the lowercase names of the tables to those of the other tables
+// their foreign keys reference. Self references and tables outside of the dump are left out,
+// since they don't constrain the order.
+func (s3d *Dumper) foreignKeyParents(ctx context.Context, db preparer, tableSchemas []schema) (map[string][]string, error) {
+	dumped := map[string]bool{}
+	for _, schema := range tableSchemas {
+		dumped[strings.ToLower(schema.Name)] = true
+	}
+
+	dependencies := map[string][]string{}
+	for _, schema := range tableSchemas {
+		name := strings.ToLower(schema.Name)
+		parents, err := s3d.pragmaForeignKeyList(ctx, db, schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			parent = strings.ToLower(parent)
+			if parent != name && dumped[parent] {
+				dependencies[name] = append(dependencies[name], parent)
+			}
+		}
+	}
+	return dependencies, nil
+}
+
+// sortTables orders the tables so that each comes after the tables it depends on, keeping their
+// order otherwise. If the dependencies form a cycle the tables are returned unchanged and cycle
+// is true.
+func sortTables(tableSchemas []schema, dependencies map[string][]string) (sorted []schema, cycle bool) {
+	emitted := map[string]bool{}
+	sorted = make([]schema, 0, len(tableSchemas))
+	for len(sorted) < len(tableSchemas) {
+		progress := false
+		for _, schema := range tableSchemas {
+			name := strings.ToLower(schema.Name)
+			if emitted[name] || !allEmitted(dependencies[name], emitted) {
+				continue
+			}
+			emitted[name] = true
+			sorted = append(sorted, schema)
+			progress = true
+			// restart so the first ready table is always picked next
+			break
+		}
+		if !progress {
+			return tableSchemas, true
+		}
+	}
+	return sorted, false
+}
+
+// dropOrder orders the objects for their DROP statements, so each is dropped before what it
+// depends on: the triggers, the views, last created first, the indexes, then the tables that
+// reference others by foreign keys before the tables they reference, since dropping a table
+// deletes its rows, which the foreign keys of the other tables may still refer to.
+func (s3d *Dumper) dropOrder(ctx context.Context, db preparer, tableSchemas, otherSchemas []schema) ([]schema, error) {
+	var triggers, views, indexes []schema
+	for _, schema := range otherSchemas {
+		switch schema.Type {
+		case "trigger":
+			triggers = append(triggers, schema)
+		case "view":
+			views = append(views, schema)
+		case "index":
+			indexes = append(indexes, schema)
+		}
+	}
+	ordered := append(triggers, make([]schema, 0, len(otherSchemas)-len(triggers)+len(tableSchemas))...)
+	for i := len(views) - 1; i >= 0; i-- {
+		ordered = append(ordered, views[i])
+	}
+	ordered = append(ordered, indexes...)
+
+	parents, err := s3d.foreignKeyParents(ctx, db, tableSchemas)
+	if err != nil {
+		return nil, err
+	}
+	children := map[string][]string{}
+	for child, names := range parents {
+		for _, parent := range names {
+			children[parent] = append(children[parent], child)
+		}
+	}
+	tables, _ := sortTables(tableSchemas, children)
+	return append(ordered, tables...), nil
+}
+
+// identifierRegexp matches the string literals and the identifiers, quoted or not, of SQL.
+var identifierRegexp = regexp.MustCompile("'(?:[^']|'')*'|\"(?:[^\"]|\"\")*\"|`(?:[^`]|``)*`|\\[[^\\]]*\\]|[A-Za-z_][A-Za-z0-9_$]*")
+
+// referencedNames returns the lowercase identifiers in the SQL, unquoted. It's a light scan
+// finding the names the statement may refer to, some of them can be column names or keywords.
+func referencedNames(sql string) map[string]bool {
+	names := map[string]bool{}
+	for _, token := range identifierRegexp.FindAllString(sql, -1) {
+		switch token[0] {
+		case '\'':
+			continue
+		case '"', '`':
+			q := token[:1]
+			token = strings.Replace(token[1:len(token)-1], q+q, q, -1)
+		case '[':
+			token = token[1 : len(token)-1]
+		}
+		names[strings.ToLower(token)] = true
+	}
+	return names
+}
+
+// sortOtherSchemas orders the indexes, triggers and views so that each can be created when
+// its turn comes: the indexes first, then the views with the views they select from before
+// them, and the triggers last, as they can refer to any table or view.
+// The order of sqlite_master is kept otherwise.
+func sortOtherSchemas(otherSchemas []schema) []schema {
+	var indexes, views, triggers []schema
+	for _, schema := range otherSchemas {
+		switch schema.Type {
+		case "index":
+			indexes = append(indexes, schema)
+		case "view":
+			views = append(views, schema)
+		default:
+			triggers = append(triggers, schema)
+		}
+	}
+
+	dependencies := map[string][]string{}
+	for _, view := range views {
+		name := strings.ToLower(view.Name)
+		referenced := referencedNames(view.SQL)
+		for _, other := range views {
+			otherName := strings.ToLower(other.Name)
+			if otherName != name && referenced[otherName] {
+				dependencies[name] = append(dependencies[name], otherName)
+			}
+		}
+	}
+
+	emitted := map[string]bool{}
+	sorted := append(make([]schema, 0, len(otherSchemas)), indexes...)
+	for remaining := len(views); remaining > 0; remaining-- {
+		next := -1
+		for i, view := range views {
+			name := strings.ToLower(view.Name)
+			if !emitted[name] && allEmitted(dependencies[name], emitted) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// a cycle, which SQLite can't select from anyway, keeps the order of sqlite_master
+			for _, view := range views {
+				if !emitted[strings.ToLower(view.Name)] {
+					sorted = append(sorted, view)
+				}
+			}
+			break
+		}
+		emitted[strings.ToLower(views[next].Name)] = true
+		sorted = append(sorted, views[next])
+	}
+	return append(sorted, triggers...)
+}
+
+func allEmitted(names []string, emitted map[string]bool) bool {
+	for _, name := range names {
+		if !emitted[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// pragmaForeignKeyList returns the names of the tables referenced by the table's foreign keys.
+func (s3d *Dumper) pragmaForeignKeyList(ctx context.Context, db preparer, schema schema) (parents []string, err error) {
+	q := `
+        PRAGMA ` + s3d.tablePrefix(schema) + `foreign_key_list(` + quoteIdent(schema.Name) + `)
+		`
+	stmt, err := db.PrepareContext(ctx, q)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, seq                       int
+			parent, from                  string
+			to, onUpdate, onDelete, match sql.NullString
+		)
+		err = rows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match)
+		if err != nil {
+			return
+		}
+		parents = append(parents, parent)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,305 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDependencyOrder(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a_child(id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES b_parent(id))`,
+		`CREATE TABLE b_parent(id INTEGER PRIMARY KEY)`,
+		`INSERT INTO b_parent VALUES(1)`,
+		`INSERT INTO a_child VALUES(1, 1)`,
+	)
+
+	got, err := DumpString(dbName, WithDependencyOrder())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE b_parent(id INTEGER PRIMARY KEY);\n" +
+		`INSERT INTO "b_parent" VALUES(1);` + "\n" +
+		"CREATE TABLE a_child(id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES b_parent(id));\n" +
+		`INSERT INTO "a_child" VALUES(1,1);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithDependencyOrderCycle(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE x(id INTEGER PRIMARY KEY, y_id INTEGER REFERENCES y(id))`,
+		`CREATE TABLE y(id INTEGER PRIMARY KEY, x_id INTEGER REFERENCES x(id))`,
+		`CREATE TABLE z(id INTEGER PRIMARY KEY, z_id INTEGER REFERENCES z(id))`,
+	)
+
+	got, err := DumpString(dbName, WithDependencyOrder())
+	require.NoError(t, err)
+
+	expect := "PRAGMA foreign_keys=OFF;\n" +
+		"BEGIN TRANSACTION;\n" +
+		"CREATE TABLE x(id INTEGER PRIMARY KEY, y_id INTEGER REFERENCES y(id));\n" +
+		"CREATE TABLE y(id INTEGER PRIMARY KEY, x_id INTEGER REFERENCES x(id));\n" +
+		"CREATE TABLE z(id INTEGER PRIMARY KEY, z_id INTEGER REFERENCES z(id));\n" +
+		"COMMIT;\n" +
+		"PRAGMA foreign_keys=ON;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestWithStableOrder(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`CREATE INDEX t_ba ON t(b, a)`,
+		`INSERT INTO t VALUES(1, 'z'), (2, 'a'), (3, 'm')`,
+		`CREATE TABLE w(x TEXT, y INTEGER, z TEXT, PRIMARY KEY(y, x)) WITHOUT ROWID`,
+		`INSERT INTO w VALUES('b', 2, 'first'), ('a', 2, 'second'), ('c', 1, 'third')`,
+		`CREATE TABLE r(rowid TEXT, n INTEGER)`,
+		`INSERT INTO r VALUES('b', 1), ('a', 2)`,
+	)
+
+	first, err := DumpString(dbName, WithStableOrder(), WithDataOnly())
+	require.NoError(t, err)
+	second, err := DumpString(dbName, WithStableOrder(), WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "r" VALUES('b',1);` + "\n" +
+		`INSERT INTO "r" VALUES('a',2);` + "\n" +
+		`INSERT INTO "t" VALUES(1,'z');` + "\n" +
+		`INSERT INTO "t" VALUES(2,'a');` + "\n" +
+		`INSERT INTO "t" VALUES(3,'m');` + "\n" +
+		`INSERT INTO "w" VALUES('c',1,'third');` + "\n" +
+		`INSERT INTO "w" VALUES('a',2,'second');` + "\n" +
+		`INSERT INTO "w" VALUES('b',2,'first');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, first)
+}
+
+func TestStableOrderExpression(t *testing.T) {
+	columns := []column{{Name: "a", PK: 2}, {Name: `b"c`, PK: 1}, {Name: "d"}}
+
+	cases := map[string]struct {
+		schema  schema
+		columns []column
+		expect  string
+	}{
+		"rowid table": {
+			schema:  schema{SQL: `CREATE TABLE t(a, "b""c", d, PRIMARY KEY(a))`},
+			columns: columns,
+			expect:  "rowid",
+		},
+		"shadowed rowid": {
+			schema:  schema{SQL: `CREATE TABLE t(rowid, _rowid_)`},
+			columns: []column{{Name: "rowid"}, {Name: "_ROWID_"}},
+			expect:  "oid",
+		},
+		"WITHOUT ROWID": {
+			schema:  schema{SQL: `CREATE TABLE t(a, "b""c", d, PRIMARY KEY("b""c", a)) without  rowid`},
+			columns: columns,
+			expect:  `"b""c","a"`,
+		},
+		"STRICT, WITHOUT ROWID": {
+			schema:  schema{SQL: "CREATE TABLE t(a, \"b\"\"c\", d, PRIMARY KEY(\"b\"\"c\", a)) STRICT,\nWITHOUT ROWID"},
+			columns: columns,
+			expect:  `"b""c","a"`,
+		},
+		"no rowid and no primary key": {
+			schema:  schema{SQL: `CREATE TABLE t(a, "b""c", d) WITHOUT ROWID`},
+			columns: []column{{Name: "a"}, {Name: `b"c`}, {Name: "d"}},
+			expect:  `"a","b""c","d"`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expect, stableOrder(c.schema, c.columns))
+		})
+	}
+}
+
+func TestWithoutRowidTable(t *testing.T) {
+	statements := []string{
+		`CREATE TABLE w(region TEXT, id INTEGER, label TEXT, PRIMARY KEY(region, id)) WITHOUT ROWID`,
+	}
+	for i := 0; i < 50; i++ {
+		statements = append(statements, fmt.Sprintf(`INSERT INTO w VALUES('%c', %d, 'row %d')`, 'a'+i%3, 50-i, i))
+	}
+	dbName := createDB(t, statements...)
+
+	for name, opts := range map[string][]Option{
+		"default":      nil,
+		"stable order": {WithStableOrder()},
+		"batches":      {WithInsertBatchSize(7), WithStableOrder()},
+		"scanned":      {WithNullAs("NULL")},
+		"concurrency":  {WithConcurrency(2)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, opts...)
+			require.NoError(t, err)
+
+			db := restoreDB(t, got)
+			var count int
+			var rows string
+			require.NoError(t, db.QueryRow(`SELECT count(*), group_concat(region || id || label) FROM (SELECT * FROM w ORDER BY region, id)`).Scan(&count, &rows))
+			assert.Equal(t, 50, count)
+
+			orig, err := sql.Open("sqlite3", dbName)
+			require.NoError(t, err)
+			defer orig.Close()
+			var expect string
+			require.NoError(t, orig.QueryRow(`SELECT group_concat(region || id || label) FROM (SELECT * FROM w ORDER BY region, id)`).Scan(&expect))
+			assert.Equal(t, expect, rows)
+		})
+	}
+
+	// the stable order is the primary key, as the table has no rowid
+	got, err := DumpString(dbName, WithStableOrder(), WithDataOnly())
+	require.NoError(t, err)
+	lines := strings.Split(got, "\n")
+	assert.Equal(t, `INSERT INTO "w" VALUES('a',2,'row 48');`, lines[1])
+	assert.Equal(t, `INSERT INTO "w" VALUES('a',5,'row 45');`, lines[2])
+}
+
+func TestViewAndTriggerOrder(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(x INTEGER)`,
+		`CREATE TABLE log(x INTEGER)`,
+		`CREATE VIEW "b view" AS SELECT 1 AS x`,
+		`CREATE VIEW a_view AS SELECT x FROM "b view"`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN INSERT INTO log SELECT x FROM c_view; END`,
+		`CREATE VIEW c_view AS SELECT x FROM a_view WHERE 'b view' != ''`,
+		// recreated, so it comes after the view selecting from it in sqlite_master
+		`DROP VIEW "b view"`,
+		`CREATE VIEW "b view" AS SELECT x FROM t`,
+		`CREATE INDEX t_x ON t(x)`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE log(x INTEGER);\n" +
+		"CREATE TABLE t(x INTEGER);\n" +
+		"CREATE INDEX t_x ON t(x);\n" +
+		`CREATE VIEW "b view" AS SELECT x FROM t;` + "\n" +
+		`CREATE VIEW a_view AS SELECT x FROM "b view";` + "\n" +
+		"CREATE VIEW c_view AS SELECT x FROM a_view WHERE 'b view' != '';\n" +
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN INSERT INTO log SELECT x FROM c_view; END;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	db := restoreDB(t, got)
+	_, err = db.Exec(`INSERT INTO t VALUES(7)`)
+	require.NoError(t, err)
+	var logged int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM log`).Scan(&logged))
+	assert.Equal(t, 1, logged)
+}
+
+func TestReferencedNames(t *testing.T) {
+	names := referencedNames("CREATE VIEW v AS SELECT \"a\"\"b\", `c`, [d e] FROM F WHERE x = 'not_a_name'")
+	for _, name := range []string{"create", "view", "v", `a"b`, "c", "d e", "f", "x"} {
+		assert.True(t, names[name], name)
+	}
+	assert.False(t, names["not_a_name"])
+}
+
+func TestWithOrderBy(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE names(id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE TABLE other(n INTEGER)`,
+		`INSERT INTO names(name) VALUES('bob'), ('Alice'), ('carol'), ('alice')`,
+		`INSERT INTO other VALUES(2), (1)`,
+	)
+
+	got, err := DumpString(dbName, WithDataOnly(), WithStableOrder(), WithOrderBy("Names", "name COLLATE NOCASE, id"), WithMaxRowsPerTable(3))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "names" VALUES(2,'Alice');` + "\n" +
+		`INSERT INTO "names" VALUES(4,'alice');` + "\n" +
+		`INSERT INTO "names" VALUES(1,'bob');` + "\n" +
+		`INSERT INTO "other" VALUES(2);` + "\n" +
+		`INSERT INTO "other" VALUES(1);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	_, err = DumpString(dbName, WithOrderBy("names", "name"), WithKeysetPagination("NAMES", "id"))
+	assert.EqualError(t, err, `WithOrderBy and WithKeysetPagination options can't be combined for table "names"`)
+}
+
+func TestDropOrder(t *testing.T) {
+	schema := []string{
+		`CREATE TABLE a_parent(id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE b_child(id INTEGER PRIMARY KEY, parent INTEGER REFERENCES a_parent(id))`,
+		`CREATE TABLE c_other(x UNIQUE)`,
+		`CREATE INDEX child_parent ON b_child(parent)`,
+		`CREATE VIEW v AS SELECT * FROM b_child`,
+		`CREATE VIEW w AS SELECT * FROM v`,
+		`CREATE TRIGGER tr AFTER INSERT ON a_parent BEGIN SELECT 1; END`,
+	}
+	dbName := createDB(t, append(schema,
+		`INSERT INTO a_parent VALUES(1)`,
+		`INSERT INTO b_child VALUES(1, 1)`,
+	)...)
+
+	got, err := DumpString(dbName, WithDropIfExists(true), WithoutData())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`DROP TRIGGER IF EXISTS "tr";` + "\n" +
+		`DROP VIEW IF EXISTS "w";` + "\n" +
+		`DROP VIEW IF EXISTS "v";` + "\n" +
+		`DROP INDEX IF EXISTS "child_parent";` + "\n" +
+		`DROP TABLE IF EXISTS "b_child";` + "\n" +
+		`DROP TABLE IF EXISTS "a_parent";` + "\n" +
+		`DROP TABLE IF EXISTS "c_other";` + "\n"
+	assert.True(t, strings.HasPrefix(got, expect), got)
+
+	// the dump replaces the objects of a database with foreign keys enforced
+	target := createDB(t, append(schema,
+		`INSERT INTO a_parent VALUES(2)`,
+		`INSERT INTO b_child VALUES(2, 2)`,
+	)...)
+	db, err := sql.Open("sqlite3", target+"?_foreign_keys=1")
+	require.NoError(t, err)
+	defer db.Close()
+	got, err = DumpString(dbName, WithDropIfExists(true))
+	require.NoError(t, err)
+	require.NoError(t, RestoreDB(db, strings.NewReader(got)))
+	var parent int
+	require.NoError(t, db.QueryRow(`SELECT parent FROM w`).Scan(&parent))
+	assert.Equal(t, 1, parent)
+}
+
+func TestAutoIndexes(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER, code TEXT UNIQUE, PRIMARY KEY(id, code))`,
+		`INSERT INTO t VALUES(1, 'a')`,
+	)
+
+	got, err := DumpString(dbName, WithDropIfExists(true))
+	require.NoError(t, err)
+	assert.NotContains(t, got, "sqlite_autoindex")
+
+	// the dump restores over itself, the indexes of the constraints are recreated with the table
+	target := createDB(t, `CREATE TABLE t(id INTEGER, code TEXT UNIQUE, PRIMARY KEY(id, code))`)
+	require.NoError(t, Restore(target, strings.NewReader(got)))
+	db, err := sql.Open("sqlite3", target)
+	require.NoError(t, err)
+	defer db.Close()
+	var indexes int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name LIKE 'sqlite_autoindex_t_%'`).Scan(&indexes))
+	assert.Equal(t, 2, indexes)
+
+	// they can't be dropped, even if listed
+	var b strings.Builder
+	require.NoError(t, New().writeDropStatements(&b, []schema{
+		{Name: "sqlite_autoindex_t_1", Type: "index", TableName: "t"},
+		{Name: "t_code", Type: "index", TableName: "t"},
+	}))
+	assert.Equal(t, `DROP INDEX IF EXISTS "t_code";`+"\n", b.String())
+}
@@ -0,0 +1,375 @@
+package sqlite3dump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// PostgresDialect writes a dump to restore into PostgreSQL, for the common schemas,
+// see WithDialect. It rewrites:
+//   - the names, whose case SQLite ignores, in lowercase, unquoted unless they aren't
+//     plain ASCII words or are reserved words of PostgreSQL, in every statement, so they
+//     match whether the CREATE statements quoted them or not;
+//   - the column types by their affinity: the integers as BIGINT, the reals as DOUBLE
+//     PRECISION, the blobs as BYTEA, DATETIME as TIMESTAMP and the columns without a type as
+//     TEXT, keeping the other types, such as VARCHAR(20), NUMERIC or BOOLEAN;
+//   - an INTEGER PRIMARY KEY column as an identity column, whose sequence is set after
+//     the rows of its table to follow their greatest value, and without AUTOINCREMENT;
+//   - the values of the BOOLEAN columns as TRUE and FALSE, and the blobs as bytea literals,
+//     leaving out the COLLATE NOCASE, BINARY and RTRIM clauses, WITHOUT ROWID and STRICT.
+//
+// The triggers, whose syntax differs, are left out, as are the internal tables of SQLite,
+// such as sqlite_sequence. What SQL can't be translated by a scan of its words is written as is
+// and can fail to restore: the functions of SQLite in the DEFAULT and CHECK clauses and in the
+// views, the bare names that are reserved words of PostgreSQL, the virtual tables, and the
+// values PostgreSQL won't convert to the type of their column, such as text in an integer
+// column. The rows of a table with a foreign key must follow those of its parent, see
+// WithDependencyOrder, PostgreSQL has no equivalent of the foreign_keys pragma.
+type PostgresDialect struct{}
+
+// postgresReserved are the reserved keywords of PostgreSQL, which can't be bare names.
+var postgresReserved = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`all analyse analyze and any array as asc asymmetric
+		authorization binary both case cast check collate collation column concurrently constraint
+		create cross current_catalog current_date current_role current_schema current_time
+		current_timestamp current_user default deferrable desc distinct do else end except false
+		fetch for foreign freeze from full grant group having ilike in initially inner intersect
+		into is isnull join lateral leading left like limit localtime localtimestamp natural not
+		notnull null offset on only or order outer overlaps placing primary references returning
+		right select session_user similar some symmetric system_user table tablesample then to
+		trailing true union unique user using variadic verbose when where window with`) {
+		postgresReserved[word] = true
+	}
+}
+
+// plainNameRegexp matches the names PostgreSQL reads unquoted, once lowercased.
+var plainNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// postgresName returns the name of the object or column in PostgreSQL, in lowercase if
+// it's read unquoted, as reported by bare, see QuoteIdent.
+func postgresName(name string) (pgName string, bare bool) {
+	if lower := strings.ToLower(name); plainNameRegexp.MatchString(lower) && !postgresReserved[lower] {
+		return lower, true
+	}
+	return name, false
+}
+
+// QuoteIdent returns the name in lowercase, which PostgreSQL reads unquoted like it does
+// the other bare names, or double-quoted if it isn't a plain ASCII word or is reserved.
+func (PostgresDialect) QuoteIdent(name string) string {
+	if pgName, bare := postgresName(name); bare {
+		return pgName
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Value returns the literal of the value, as TRUE or FALSE for the 1 and 0 of a BOOLEAN
+// column, as a bytea literal for a blob, and like quote() of SQLite otherwise.
+func (PostgresDialect) Value(v interface{}, declaredType string) string {
+	switch v := v.(type) {
+	case int64:
+		if boolType(declaredType) && (v == 0 || v == 1) {
+			if v == 1 {
+				return "TRUE"
+			}
+			return "FALSE"
+		}
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "'NaN'::double precision"
+		case math.IsInf(v, 1):
+			return "'Infinity'::double precision"
+		case math.IsInf(v, -1):
+			return "'-Infinity'::double precision"
+		}
+	case []byte:
+		return `'\x` + hex.EncodeToString(v) + `'::bytea`
+	}
+	return QuoteValue(v)
+}
+
+// boolType reports whether the declared type is a boolean one.
+func boolType(declaredType string) bool {
+	switch strings.ToUpper(strings.TrimSpace(declaredType)) {
+	case "BOOL", "BOOLEAN":
+		return true
+	}
+	return false
+}
+
+// Create rewrites the statement, see PostgresDialect, leaving out the triggers.
+func (d PostgresDialect) Create(objType, name, sql string) string {
+	if objType == "trigger" {
+		return ""
+	}
+	tokens := d.rewriteNames(sqlTokens(sql))
+	if objType == "table" {
+		tokens = d.rewriteColumns(tokens)
+	}
+	return strings.Join(tokens, "")
+}
+
+// rewriteNames quotes the quoted names like QuoteIdent, and removes AUTOINCREMENT and the
+// collations PostgreSQL doesn't have.
+func (d PostgresDialect) rewriteNames(tokens []string) []string {
+	rewritten := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token[0] {
+		case '"', '`', '[':
+			rewritten = append(rewritten, d.QuoteIdent(unquoteName(token)))
+			continue
+		}
+		switch strings.ToUpper(token) {
+		case "AUTOINCREMENT":
+			rewritten = trimSpaceTokens(rewritten)
+			continue
+		case "COLLATE":
+			if next := nextWord(tokens, i+1); next > 0 {
+				switch strings.ToUpper(tokens[next]) {
+				case "NOCASE", "BINARY", "RTRIM":
+					rewritten = trimSpaceTokens(rewritten)
+					i = next
+					continue
+				}
+			}
+		}
+		rewritten = append(rewritten, token)
+	}
+	return rewritten
+}
+
+// columnConstraintWords are the words that end the type of a column definition.
+var columnConstraintWords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "NOT": true, "NULL": true, "UNIQUE": true, "CHECK": true,
+	"DEFAULT": true, "COLLATE": true, "REFERENCES": true, "GENERATED": true, "AS": true,
+}
+
+// tableConstraintWords are the words that start a table constraint rather than a column.
+var tableConstraintWords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true, "FOREIGN": true,
+}
+
+// rewriteColumns rewrites the types of the columns of a CREATE TABLE statement, and removes
+// the table options that follow its columns, such as WITHOUT ROWID.
+func (d PostgresDialect) rewriteColumns(tokens []string) []string {
+	rewritten := []string{}
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		rewritten = append(rewritten, token)
+		switch token {
+		case "(", ",":
+			if token == "(" {
+				depth++
+			}
+			if depth != 1 {
+				continue
+			}
+			// a column definition starts with its name, then its type
+			nameIndex := nextWord(tokens, i+1)
+			if nameIndex < 0 || tableConstraintWords[strings.ToUpper(tokens[nameIndex])] {
+				continue
+			}
+			typeStart := nameIndex + 1
+			typeEnd := columnTypeEnd(tokens, typeStart)
+			declared := strings.ToUpper(strings.Join(strings.Fields(strings.Join(tokens[typeStart:typeEnd], " ")), " "))
+			pgType := postgresType(declared, strings.TrimSpace(strings.Join(tokens[typeStart:typeEnd], "")))
+			if declared == "INTEGER" && rowidAlias(tokens[typeEnd:]) {
+				pgType = "BIGINT GENERATED BY DEFAULT AS IDENTITY"
+			}
+			rewritten = append(rewritten, tokens[i+1:nameIndex+1]...)
+			rewritten = append(rewritten, " "+pgType)
+			// the whitespace after the type is kept
+			i = typeEnd - 1
+		case ")":
+			depth--
+			if depth == 0 {
+				// the table options, WITHOUT ROWID and STRICT, are left out
+				return rewritten
+			}
+		}
+	}
+	return rewritten
+}
+
+// columnTypeEnd returns the index of the token after the type of a column starting
+// at start: its words and their parenthesized arguments, up to a constraint or the end
+// of the definition.
+func columnTypeEnd(tokens []string, start int) int {
+	i := start
+	for i < len(tokens) {
+		token := tokens[i]
+		switch {
+		case strings.TrimSpace(token) == "" || strings.HasPrefix(token, "--") || strings.HasPrefix(token, "/*"):
+			i++
+		case token == "(":
+			depth := 0
+			for ; i < len(tokens); i++ {
+				if tokens[i] == "(" {
+					depth++
+				} else if tokens[i] == ")" {
+					if depth--; depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+		case isWordToken(token) && !columnConstraintWords[strings.ToUpper(token)]:
+			i++
+		default:
+			// leave the whitespace before the constraint or the end
+			for i > start && strings.TrimSpace(tokens[i-1]) == "" {
+				i--
+			}
+			return i
+		}
+	}
+	return i
+}
+
+// rowidAlias reports whether the column definition whose constraints are the tokens makes
+// an INTEGER column the alias of the rowid: its PRIMARY KEY, not DESC.
+func rowidAlias(tokens []string) bool {
+	words := []string{}
+	depth := 0
+	for _, token := range tokens {
+		if depth == 0 && (token == "," || token == ")") {
+			break
+		}
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && isWordToken(token) {
+			words = append(words, strings.ToUpper(token))
+		}
+	}
+	for i := 0; i+1 < len(words); i++ {
+		if words[i] == "PRIMARY" && words[i+1] == "KEY" {
+			return i+2 >= len(words) || words[i+2] != "DESC"
+		}
+	}
+	return false
+}
+
+// postgresType returns the type of PostgreSQL for the declared type of SQLite, in uppercase
+// with single spaces, by its affinity. original is the declared type as written.
+func postgresType(declared, original string) string {
+	name := declared
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	switch {
+	case declared == "":
+		return "TEXT"
+	case name == "BOOL" || name == "BOOLEAN":
+		return "BOOLEAN"
+	case strings.Contains(name, "INT"):
+		return "BIGINT"
+	case strings.Contains(name, "CHAR") || strings.Contains(name, "CLOB") || strings.Contains(name, "TEXT"):
+		switch name {
+		case "TEXT", "CHAR", "CHARACTER", "VARCHAR", "CHARACTER VARYING":
+			return original
+		}
+		return "TEXT"
+	case strings.Contains(name, "BLOB"):
+		return "BYTEA"
+	case strings.Contains(name, "REAL") || strings.Contains(name, "FLOA") || strings.Contains(name, "DOUB"):
+		return "DOUBLE PRECISION"
+	case name == "DATETIME":
+		return "TIMESTAMP"
+	}
+	return original
+}
+
+// AfterRows sets the sequence of the identity column of the table, if it has one, to follow
+// the greatest value of its rows.
+func (d PostgresDialect) AfterRows(table, sql string) string {
+	column := d.identityColumn(sql)
+	if column == "" {
+		return ""
+	}
+	// the table name is read as a name, quoted or not, the column name as it is
+	pgColumn, _ := postgresName(column)
+	return fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), coalesce(max(%s), 0) + 1, false) FROM %s;\n",
+		QuoteValue(d.QuoteIdent(table)), QuoteValue(pgColumn), d.QuoteIdent(column), d.QuoteIdent(table))
+}
+
+// identityColumn returns the name of the INTEGER PRIMARY KEY column of the CREATE TABLE
+// statement, or "".
+func (d PostgresDialect) identityColumn(sql string) string {
+	tokens := sqlTokens(sql)
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 1 || (token != "(" && token != ",") {
+			continue
+		}
+		nameIndex := nextWord(tokens, i+1)
+		if nameIndex < 0 || tableConstraintWords[strings.ToUpper(tokens[nameIndex])] {
+			continue
+		}
+		typeEnd := columnTypeEnd(tokens, nameIndex+1)
+		declared := strings.ToUpper(strings.TrimSpace(strings.Join(tokens[nameIndex+1:typeEnd], "")))
+		if declared == "INTEGER" && rowidAlias(tokens[typeEnd:]) {
+			return unquoteName(tokens[nameIndex])
+		}
+	}
+	return ""
+}
+
+// unquoteName returns the name of a word or quoted identifier token.
+func unquoteName(token string) string {
+	switch token[0] {
+	case '"', '`':
+		q := token[:1]
+		return strings.Replace(token[1:len(token)-1], q+q, q, -1)
+	case '[':
+		return token[1 : len(token)-1]
+	}
+	return token
+}
+
+// isWordToken reports whether the token is a word or a quoted identifier.
+func isWordToken(token string) bool {
+	c := token[0]
+	return c == '_' || c == '"' || c == '`' || c == '[' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+// nextWord returns the index of the first word or quoted identifier from i, skipping
+// whitespace and comments, or -1 if there's something else first.
+func nextWord(tokens []string, i int) int {
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		if strings.TrimSpace(token) == "" || strings.HasPrefix(token, "--") || strings.HasPrefix(token, "/*") {
+			continue
+		}
+		if isWordToken(token) {
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// trimSpaceTokens removes the whitespace tokens ending the tokens.
+func trimSpaceTokens(tokens []string) []string {
+	for len(tokens) > 0 && strings.TrimSpace(tokens[len(tokens)-1]) == "" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
@@ -0,0 +1,110 @@
+package sqlite3dump
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresQuoteIdent(t *testing.T) {
+	for name, expect := range map[string]string{
+		"users":     "users",
+		"Users":     "users",
+		"_id2":      "_id2",
+		"user":      `"user"`,
+		"Order":     `"Order"`,
+		"two words": `"two words"`,
+		`a"b`:       `"a""b"`,
+		"2fa":       `"2fa"`,
+		"naïve":     `"naïve"`,
+		"a-b":       `"a-b"`,
+	} {
+		assert.Equal(t, expect, PostgresDialect{}.QuoteIdent(name), name)
+	}
+}
+
+func TestPostgresValue(t *testing.T) {
+	d := PostgresDialect{}
+	assert.Equal(t, "TRUE", d.Value(int64(1), "BOOLEAN"))
+	assert.Equal(t, "FALSE", d.Value(int64(0), "bool"))
+	assert.Equal(t, "2", d.Value(int64(2), "BOOLEAN"))
+	assert.Equal(t, "1", d.Value(int64(1), "INTEGER"))
+	assert.Equal(t, `'\x00ff'::bytea`, d.Value([]byte{0, 0xff}, "BLOB"))
+	assert.Equal(t, "'it''s'", d.Value("it's", "TEXT"))
+	assert.Equal(t, "1.5", d.Value(1.5, "REAL"))
+	assert.Equal(t, "'-Infinity'::double precision", d.Value(math.Inf(-1), "REAL"))
+	assert.Equal(t, "NULL", d.Value(nil, "BOOLEAN"))
+}
+
+func TestPostgresCreate(t *testing.T) {
+	cases := []struct {
+		objType, sql, expect string
+	}{
+		{
+			"table",
+			`CREATE TABLE "Users"(id INTEGER PRIMARY KEY AUTOINCREMENT, [Name] VARCHAR(20) NOT NULL COLLATE NOCASE, age INT(11) DEFAULT 0, score REAL, avatar BLOB, active BOOLEAN, note, created DATETIME, "user" TEXT)`,
+			`CREATE TABLE users(id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, name VARCHAR(20) NOT NULL, age BIGINT DEFAULT 0, score DOUBLE PRECISION, avatar BYTEA, active BOOLEAN, note TEXT, created TIMESTAMP, "user" TEXT)`,
+		},
+		{
+			"table",
+			"CREATE TABLE `kv`(`key` TEXT PRIMARY KEY, value, UNIQUE(value)) WITHOUT ROWID",
+			`CREATE TABLE kv(key TEXT PRIMARY KEY, value TEXT, UNIQUE(value))`,
+		},
+		{
+			"table",
+			`CREATE TABLE child(id INTEGER PRIMARY KEY DESC, parent INTEGER REFERENCES "Users"(id) ON DELETE CASCADE, CHECK(parent > 0))`,
+			`CREATE TABLE child(id BIGINT PRIMARY KEY DESC, parent BIGINT REFERENCES users(id) ON DELETE CASCADE, CHECK(parent > 0))`,
+		},
+		{
+			"index",
+			`CREATE INDEX "Users_Name" ON "Users"("Name" COLLATE NOCASE)`,
+			`CREATE INDEX users_name ON users(name)`,
+		},
+		{
+			"view",
+			`CREATE VIEW "Adults" AS SELECT "Name" FROM "Users" WHERE age >= 18`,
+			`CREATE VIEW adults AS SELECT name FROM users WHERE age >= 18`,
+		},
+		{
+			"trigger",
+			`CREATE TRIGGER t AFTER INSERT ON "Users" BEGIN SELECT 1; END`,
+			"",
+		},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expect, PostgresDialect{}.Create(c.objType, "", c.sql), c.sql)
+	}
+}
+
+func TestWithDialect(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE "Users"(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, active BOOLEAN, avatar BLOB)`,
+		`CREATE TABLE tags(name TEXT COLLATE NOCASE)`,
+		`CREATE INDEX users_name ON "Users"(name)`,
+		`CREATE TRIGGER tr AFTER INSERT ON "Users" BEGIN SELECT 1; END`,
+		`INSERT INTO "Users"(name, active, avatar) VALUES('alice', 1, x'0102'), ('bob', 0, NULL)`,
+		`INSERT INTO tags VALUES('Go')`,
+		`ANALYZE`,
+	)
+
+	got, err := DumpString(dbName, WithDialect(PostgresDialect{}), WithDropIfExists(true), WithColumnNames())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"DROP INDEX IF EXISTS users_name;\n" +
+		"DROP TABLE IF EXISTS users;\n" +
+		"DROP TABLE IF EXISTS tags;\n" +
+		"CREATE TABLE users(id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, name TEXT, active BOOLEAN, avatar BYTEA);\n" +
+		`INSERT INTO users(id,name,active,avatar) VALUES(1,'alice',TRUE,'\x0102'::bytea);` + "\n" +
+		`INSERT INTO users(id,name,active,avatar) VALUES(2,'bob',FALSE,NULL);` + "\n" +
+		"SELECT setval(pg_get_serial_sequence('users', 'id'), coalesce(max(id), 0) + 1, false) FROM users;\n" +
+		"CREATE TABLE tags(name TEXT);\n" +
+		`INSERT INTO tags(name) VALUES('Go');` + "\n" +
+		"CREATE INDEX users_name ON users(name);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	_, err = DumpString(dbName, WithDialect(PostgresDialect{}), WithInsertVerb("INSERT OR REPLACE"))
+	assert.EqualError(t, err, `the insert verb "INSERT OR REPLACE" can't be combined with the WithDialect option`)
+}
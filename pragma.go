@@ -0,0 +1,49 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// statePragmas are the pragmas WithPragmaState dumps by default, in the order they're written.
+var statePragmas = []string{"user_version", "application_id"}
+
+// writePragmaState writes the PRAGMA statements setting the user_version and application_id
+// of the dumped database, see WithPragmaState.
+func (s3d *Dumper) writePragmaState(ctx context.Context, w io.Writer, db preparer) error {
+	names, explicit := s3d.pragmaStateNames, len(s3d.pragmaStateNames) > 0
+	if !explicit {
+		names = statePragmas
+	}
+	for _, name := range names {
+		stmt, err := db.PrepareContext(ctx, `PRAGMA `+s3d.schemaPrefix()+name)
+		if err != nil {
+			return err
+		}
+		var value int64
+		err = stmt.QueryRowContext(ctx).Scan(&value)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+		// zero is the value of a new database, so it's only written when asked for
+		if value == 0 && !explicit {
+			continue
+		}
+		if err = writeStatement(w, fmt.Sprintf("PRAGMA %s = %d;\n", name, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePragmaState reports the pragmas WithPragmaState can't dump.
+func validatePragmaState(names []string) error {
+	for _, name := range names {
+		if name != "user_version" && name != "application_id" {
+			return fmt.Errorf("unsupported pragma %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sqlite3dump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPragmaState(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`PRAGMA user_version = 42`,
+	)
+
+	got, err := DumpString(dbName, WithPragmaState(), WithHeaderText("dump"))
+	require.NoError(t, err)
+	expect := "-- dump\n" +
+		"PRAGMA user_version = 42;\n" +
+		"BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(a INTEGER);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	db := restoreDB(t, got)
+	var userVersion int
+	require.NoError(t, db.QueryRow(`PRAGMA user_version`).Scan(&userVersion))
+	assert.Equal(t, 42, userVersion)
+
+	// the named pragmas are written even when zero
+	got, err = DumpString(dbName, WithPragmaState("APPLICATION_ID"), WithoutData())
+	require.NoError(t, err)
+	assert.Equal(t, "PRAGMA application_id = 0;\nBEGIN TRANSACTION;\nCREATE TABLE t(a INTEGER);\nCOMMIT;\n", got)
+
+	got, err = DumpString(dbName)
+	require.NoError(t, err)
+	assert.NotContains(t, got, "PRAGMA")
+
+	_, err = DumpString(dbName, WithPragmaState("journal_mode"))
+	assert.EqualError(t, err, `unsupported pragma "journal_mode"`)
+}
@@ -0,0 +1,48 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// beginner is implemented by *sql.DB and *sql.Conn, the snapshot of a dump begins on either.
+type beginner interface {
+	preparer
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// queryOnly runs fn with the pool of db, or with WithQueryOnly, with a connection of the pool
+// on which PRAGMA query_only is set while fn runs, so nothing fn queries writes to the database.
+func (s3d *Dumper) queryOnly(ctx context.Context, db *sql.DB, fn func(db beginner) error) (err error) {
+	if !s3d.queryOnlyConn {
+		return fn(db)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var wasQueryOnly bool
+	if err = conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&wasQueryOnly); err != nil {
+		return err
+	}
+	if !wasQueryOnly {
+		if _, err = conn.ExecContext(ctx, "PRAGMA query_only=ON"); err != nil {
+			return err
+		}
+		// the connection goes back to the pool as it was, even once ctx is done,
+		// or it's discarded rather than left unable to write
+		defer func() {
+			_, resetErr := conn.ExecContext(context.Background(), "PRAGMA query_only=OFF")
+			if resetErr != nil {
+				conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			}
+			if err == nil {
+				err = resetErr
+			}
+		}()
+	}
+	return fn(conn)
+}
@@ -0,0 +1,53 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQueryOnly(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`CREATE TABLE u(b TEXT)`,
+		`INSERT INTO t VALUES(1), (2)`,
+		`INSERT INTO u VALUES('x')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	// a single connection, so the writes after the dump are on the one it ran on
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	err = New(WithQueryOnly()).queryOnly(ctx, db, func(conn beginner) error {
+		_, err := conn.(*sql.Conn).ExecContext(ctx, `INSERT INTO t VALUES(3)`)
+		return err
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readonly database")
+
+	expect, err := DumpString(dbName)
+	require.NoError(t, err)
+	for name, opts := range map[string][]Option{
+		"default":          {WithQueryOnly()},
+		"concurrent":       {WithQueryOnly(), WithConcurrency(2)},
+		"without snapshot": {WithQueryOnly(), WithoutSnapshot()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got strings.Builder
+			require.NoError(t, DumpDB(db, &got, opts...))
+			assert.Equal(t, expect, got.String())
+
+			// the connection can write again
+			_, err = db.Exec(`INSERT INTO u VALUES('y')`)
+			require.NoError(t, err)
+			_, err = db.Exec(`DELETE FROM u WHERE b = 'y'`)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package sqlite3dump
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// quoteReal formats the float like quote() of SQLite: with 15 significant digits, or else
+// with 20 decimals in exponent notation, as SQLite doesn't read every float back from its
+// 17 shortest digits, and always a decimal point. The digits may differ from those of
+// quote(), which computes them in the long double arithmetic of the C compiler, but they
+// read back as the same float64 with strconv.ParseFloat. SQLite reads a few of them, mostly
+// below 1e-290, back as a neighbouring float, unlike the digits of quote().
+//
+// Unlike quote(), which writes Inf and -Inf, the infinities are written as 9.0e+999 and
+// -9.0e+999, which overflow to them when read back, like newer versions of SQLite do.
+// NaN, which SQLite stores as NULL, is written as NULL.
+func quoteReal(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "9.0e+999"
+	case math.IsInf(f, -1):
+		return "-9.0e+999"
+	case math.IsNaN(f):
+		return "NULL"
+	}
+	s := strconv.FormatFloat(f, 'g', 15, 64)
+	if parsed, err := strconv.ParseFloat(s, 64); err != nil || parsed != f {
+		s = strconv.FormatFloat(f, 'e', 20, 64)
+	}
+	mantissa, exponent := s, ""
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		mantissa, exponent = s[:i], s[i:]
+	}
+	if !strings.ContainsRune(mantissa, '.') {
+		mantissa += ".0"
+	}
+	return mantissa + exponent
+}
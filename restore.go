@@ -0,0 +1,190 @@
+package sqlite3dump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Restore executes the SQL statements read from in, such as a dump, in the database,
+// which is created if it doesn't exist.
+func Restore(dbName string, in io.Reader) (err error) {
+	db, err := sql.Open("sqlite3", dbName)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	return RestoreDB(db, in)
+}
+
+// RestoreDB executes the SQL statements read from in, such as a dump, in a raw sql.DB.
+// The statements are executed one by one on a single connection, so the transaction
+// and the pragmas of a dump apply to the statements that follow them.
+// If a statement fails, an open transaction is rolled back.
+func RestoreDB(db *sql.DB, in io.Reader) (err error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	err = splitStatements(in, func(statement string) error {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", statement, err)
+		}
+		return nil
+	})
+	if err != nil {
+		// the error of a dump without a transaction, where there is nothing to roll back, is ignored
+		conn.ExecContext(ctx, "ROLLBACK")
+	}
+	return
+}
+
+// splitStatements reads the SQL statements from in and calls fn with each of them.
+// Semicolons within string literals, quoted identifiers and comments don't end a statement,
+// nor do those within the body of a CREATE TRIGGER, which ends with the END of its BEGIN
+// followed by a semicolon, rather than that of a CASE expression.
+func splitStatements(in io.Reader, fn func(statement string) error) error {
+	r := bufio.NewReader(in)
+
+	var (
+		statement strings.Builder
+		word      strings.Builder
+		// firstWords holds the first keywords of the statement, to detect CREATE TRIGGER
+		firstWords []string
+		// lastToken is the last word of the statement, uppercased, or "" if it was something else
+		lastToken string
+		hasToken  bool
+		// depth counts the BEGIN and CASE of a CREATE TRIGGER not yet closed by an END
+		depth int
+	)
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		if len(firstWords) < 3 {
+			firstWords = append(firstWords, w)
+		}
+		lastToken = w
+		word.Reset()
+		if isCreateTrigger(firstWords) {
+			switch w {
+			case "BEGIN", "CASE":
+				depth++
+			case "END":
+				depth--
+			}
+		}
+	}
+	// copyUntil copies the runes up to and including end into the statement,
+	// preceded by the rune before if it isn't 0
+	copyUntil := func(before, end rune) error {
+		var prev rune
+		for {
+			c, _, err := r.ReadRune()
+			if err != nil {
+				return err
+			}
+			statement.WriteRune(c)
+			if c == end && (before == 0 || prev == before) {
+				return nil
+			}
+			prev = c
+		}
+	}
+	next := func() byte {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0
+		}
+		return b[0]
+	}
+
+	// the byte order mark of WithUTF8BOM isn't part of the first statement
+	if c, _, err := r.ReadRune(); err == nil && c != '\uFEFF' {
+		r.UnreadRune()
+	}
+
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		statement.WriteRune(c)
+
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			endWord()
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			// a doubled quote escapes the quote, it reads as two adjacent literals
+			if err = copyUntil(0, end); err != nil && err != io.EOF {
+				return err
+			}
+			lastToken, hasToken = "", true
+		case c == '-' && next() == '-':
+			endWord()
+			if err = copyUntil(0, '\n'); err != nil && err != io.EOF {
+				return err
+			}
+		case c == '/' && next() == '*':
+			endWord()
+			statement.WriteRune(rune(next()))
+			r.ReadByte()
+			if err = copyUntil('*', '/'); err != nil && err != io.EOF {
+				return err
+			}
+		case c == ';':
+			endWord()
+			if isCreateTrigger(firstWords) && (lastToken != "END" || depth > 0) {
+				lastToken = ""
+				continue
+			}
+			if hasToken {
+				if err = fn(statement.String()); err != nil {
+					return err
+				}
+			}
+			statement.Reset()
+			firstWords, lastToken, hasToken, depth = nil, "", false, 0
+		case c == '_' || c == '$' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			word.WriteRune(c)
+			hasToken = true
+		default:
+			endWord()
+			if !unicode.IsSpace(c) {
+				lastToken, hasToken = "", true
+			}
+		}
+	}
+
+	// the last statement may lack its semicolon
+	if hasToken {
+		return fn(statement.String())
+	}
+	return nil
+}
+
+// isCreateTrigger reports whether the first keywords of a statement start a CREATE TRIGGER.
+func isCreateTrigger(firstWords []string) bool {
+	if len(firstWords) < 2 || firstWords[0] != "CREATE" {
+		return false
+	}
+	if firstWords[1] == "TEMP" || firstWords[1] == "TEMPORARY" {
+		return len(firstWords) > 2 && firstWords[2] == "TRIGGER"
+	}
+	return firstWords[1] == "TRIGGER"
+}
@@ -0,0 +1,201 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	in := `-- header
+BEGIN TRANSACTION;
+INSERT INTO "t;1" VALUES('a;b','it''s; "x"');
+CREATE TABLE t2(
+  a /* ; */ INTEGER, -- ;
+  [b;] TEXT
+);
+CREATE TRIGGER tr AFTER INSERT ON t2 BEGIN
+  UPDATE t2 SET a = 1;
+  DELETE FROM t2 WHERE a = (SELECT CASE WHEN 1 THEN 2 END);
+END;
+CREATE TEMP TRIGGER tr2 AFTER DELETE ON t2 BEGIN SELECT 1; END;
+CREATE TRIGGER tr3 AFTER INSERT ON t2 BEGIN UPDATE t2 SET a = CASE WHEN new.a THEN 1 ELSE 2 END; SELECT 1; END;
+-- trailing comment
+SELECT 'no semicolon'`
+
+	var got []string
+	err := splitStatements(strings.NewReader(in), func(statement string) error {
+		got = append(got, strings.TrimSpace(statement))
+		return nil
+	})
+	require.NoError(t, err)
+
+	expect := []string{
+		"-- header\nBEGIN TRANSACTION;",
+		`INSERT INTO "t;1" VALUES('a;b','it''s; "x"');`,
+		"CREATE TABLE t2(\n  a /* ; */ INTEGER, -- ;\n  [b;] TEXT\n);",
+		"CREATE TRIGGER tr AFTER INSERT ON t2 BEGIN\n  UPDATE t2 SET a = 1;\n  DELETE FROM t2 WHERE a = (SELECT CASE WHEN 1 THEN 2 END);\nEND;",
+		"CREATE TEMP TRIGGER tr2 AFTER DELETE ON t2 BEGIN SELECT 1; END;",
+		"CREATE TRIGGER tr3 AFTER INSERT ON t2 BEGIN UPDATE t2 SET a = CASE WHEN new.a THEN 1 ELSE 2 END; SELECT 1; END;",
+		"-- trailing comment\nSELECT 'no semicolon'",
+	}
+	assert.Equal(t, expect, got)
+}
+
+func TestSplitStatementsSkipsComments(t *testing.T) {
+	var got []string
+	err := splitStatements(strings.NewReader("SELECT 1;\n-- only a comment\n/* ; */\n"), func(statement string) error {
+		got = append(got, strings.TrimSpace(statement))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SELECT 1;"}, got)
+}
+
+func TestRestoreRoundTrip(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE parent(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`CREATE TABLE child(id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent(id), note TEXT, data BLOB)`,
+		`CREATE INDEX child_parent ON child(parent_id)`,
+		`CREATE VIEW v AS SELECT name FROM parent`,
+		`CREATE TRIGGER tr AFTER INSERT ON child BEGIN
+			UPDATE parent SET name = name || ';' WHERE id = new.parent_id;
+			SELECT 1;
+		END`,
+		`INSERT INTO parent(name) VALUES('a;b'), ('it''s'), ('multi
+line')`,
+		`INSERT INTO child(parent_id, note, data) VALUES(1, '-- not a comment', x'00ff'), (2, '/* nor this */', NULL)`,
+	)
+
+	var b strings.Builder
+	require.NoError(t, Dump(dbName, &b))
+
+	restored := filepath.Join(t.TempDir(), "restored.db")
+	require.NoError(t, Restore(restored, strings.NewReader(b.String())))
+
+	orig, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer orig.Close()
+	db, err := sql.Open("sqlite3", restored)
+	require.NoError(t, err)
+	defer db.Close()
+
+	schemaOf := func(db *sql.DB) []string {
+		rows, err := db.Query(`SELECT "type", "name", "sql" FROM "sqlite_master" WHERE "sql" NOT NULL ORDER BY "name"`)
+		require.NoError(t, err)
+		defer rows.Close()
+		var schemas []string
+		for rows.Next() {
+			var typ, name, sql string
+			require.NoError(t, rows.Scan(&typ, &name, &sql))
+			schemas = append(schemas, typ+" "+name+" "+sql)
+		}
+		require.NoError(t, rows.Err())
+		return schemas
+	}
+	assert.Equal(t, schemaOf(orig), schemaOf(db))
+
+	for _, table := range []string{"parent", "child", "sqlite_sequence"} {
+		var want, got int
+		q := fmt.Sprintf(`SELECT count(*) FROM "%s"`, table)
+		require.NoError(t, orig.QueryRow(q).Scan(&want))
+		require.NoError(t, db.QueryRow(q).Scan(&got))
+		assert.Equal(t, want, got, table)
+	}
+
+	// the trigger appended a semicolon to the names of the parents of the children
+	var names string
+	require.NoError(t, db.QueryRow(`SELECT group_concat(name, '|') FROM (SELECT name FROM parent ORDER BY id)`).Scan(&names))
+	assert.Equal(t, "a;b;|it's;|multi\nline", names)
+}
+
+func TestRestoreDBError(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "restored.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	err = RestoreDB(db, strings.NewReader("BEGIN TRANSACTION;\nCREATE TABLE t(n);\nINSERT INTO missing VALUES(1);\nCOMMIT;\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "INSERT INTO missing VALUES(1);")
+
+	// the transaction was rolled back
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM "sqlite_master"`).Scan(&n))
+	assert.Equal(t, 0, n)
+}
+
+func TestRestoreUTF8BOM(t *testing.T) {
+	got, err := DumpString("testdata/cars.db", WithUTF8BOM())
+	require.NoError(t, err)
+
+	restored := filepath.Join(t.TempDir(), "restored.db")
+	require.NoError(t, Restore(restored, strings.NewReader(got)))
+}
+
+func TestRestoreMultiStatementTrigger(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, n INTEGER, status TEXT)`,
+		`CREATE TABLE audit(t_id INTEGER, msg TEXT)`,
+		`CREATE TRIGGER t_insert AFTER INSERT ON t BEGIN
+			INSERT INTO audit VALUES(new.id, 'inserted; n=' || new.n);
+			UPDATE t SET status = CASE WHEN new.n > 1 THEN 'big' ELSE 'small' END WHERE id = new.id;
+			INSERT INTO audit SELECT new.id, 'END';
+			UPDATE audit SET msg = msg || CASE WHEN new.n > 1 THEN '!' ELSE '' END;
+		END`,
+		`INSERT INTO t(id, n) VALUES(1, 5)`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+	assert.Contains(t, got, "\t\tEND;\nCOMMIT;\n")
+
+	restorers := map[string]func(dump string) *sql.DB{
+		"Restore": func(dump string) *sql.DB {
+			restoredName := filepath.Join(t.TempDir(), "restored.db")
+			require.NoError(t, Restore(restoredName, strings.NewReader(dump)))
+			db, err := sql.Open("sqlite3", restoredName)
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+			return db
+		},
+		"Exec": func(dump string) *sql.DB {
+			return restoreDB(t, dump)
+		},
+	}
+	for name, restore := range restorers {
+		t.Run(name, func(t *testing.T) {
+			db := restore(got)
+
+			// the restored rows don't fire the trigger again, it's created after them
+			var audits int
+			require.NoError(t, db.QueryRow(`SELECT count(*) FROM audit`).Scan(&audits))
+			assert.Equal(t, 2, audits)
+			var first string
+			require.NoError(t, db.QueryRow(`SELECT msg FROM audit WHERE t_id = 1 ORDER BY rowid`).Scan(&first))
+			assert.Equal(t, "inserted; n=5!", first)
+
+			_, err := db.Exec(`INSERT INTO t(id, n) VALUES(2, 0)`)
+			require.NoError(t, err)
+			var status string
+			require.NoError(t, db.QueryRow(`SELECT status FROM t WHERE id = 2`).Scan(&status))
+			assert.Equal(t, "small", status)
+
+			var msgs []string
+			rows, err := db.Query(`SELECT msg FROM audit WHERE t_id = 2 ORDER BY rowid`)
+			require.NoError(t, err)
+			defer rows.Close()
+			for rows.Next() {
+				var msg string
+				require.NoError(t, rows.Scan(&msg))
+				msgs = append(msgs, msg)
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, []string{"inserted; n=0", "END"}, msgs)
+		})
+	}
+}
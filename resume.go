@@ -0,0 +1,70 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// resumedTables returns the tables of the dump from the table of WithResumeFrom on,
+// or all of them without it.
+func (s3d *Dumper) resumedTables(tableSchemas []schema) ([]schema, error) {
+	if s3d.resumeTable == "" {
+		return tableSchemas, nil
+	}
+	for i, schema := range tableSchemas {
+		if !strings.EqualFold(schema.Name, s3d.resumeTable) {
+			continue
+		}
+		if withoutRowidRegexp.MatchString(schema.SQL) {
+			return nil, fmt.Errorf("can't resume from WITHOUT ROWID table %q", schema.Name)
+		}
+		return tableSchemas[i:], nil
+	}
+	return nil, fmt.Errorf("table %q to resume from isn't dumped", s3d.resumeTable)
+}
+
+// resumesFrom reports whether the dump resumes from the table, whose CREATE statement and
+// rows up to the rowid of WithResumeFrom are left out.
+func (s3d *Dumper) resumesFrom(table string) bool {
+	return s3d.resumeTable != "" && strings.EqualFold(table, s3d.resumeTable)
+}
+
+// resumePoint is where a failed dump resumes, see Stats.
+type resumePoint struct {
+	table      string
+	afterRowid int64
+}
+
+// resumeStart returns the point a dump failing in the rows of the table resumes from
+// before any of them is written, or none if its rows aren't written in rowid order.
+func (s3d *Dumper) resumeStart(schema schema) resumePoint {
+	name := strings.ToLower(schema.Name)
+	_, hasOrder := s3d.orderBy[name]
+	_, paginated := s3d.keysetColumns[name]
+	if withoutRowidRegexp.MatchString(schema.SQL) || hasOrder || paginated || s3d.rowLimit(schema.Name) > 0 {
+		return resumePoint{}
+	}
+	if s3d.resumesFrom(schema.Name) {
+		return resumePoint{table: schema.Name, afterRowid: s3d.resumeAfter}
+	}
+	if !s3d.stableOrder {
+		return resumePoint{}
+	}
+	return resumePoint{table: schema.Name, afterRowid: math.MinInt64}
+}
+
+// resumeRowid returns the alias of the rowid the rows of the table are ordered by,
+// or "" if all of them are shadowed by columns.
+func (s3d *Dumper) resumeRowid(ctx context.Context, db preparer, schema schema) (string, error) {
+	columns, err := s3d.tableColumns(ctx, db, schema)
+	if err != nil {
+		return "", err
+	}
+	switch alias := stableOrder(schema, columns); alias {
+	case "rowid", "_rowid_", "oid":
+		return alias, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,149 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResumeFrom(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY, x TEXT)`,
+		`CREATE TABLE b(id INTEGER PRIMARY KEY, y TEXT)`,
+		`CREATE TABLE c(z)`,
+		`CREATE INDEX b_y ON b(y)`,
+		`INSERT INTO a VALUES(1, 'a1'), (2, 'a2')`,
+		`INSERT INTO b VALUES(3, 'b3'), (1, 'b1'), (2, 'b2'), (4, 'b4')`,
+		`INSERT INTO c VALUES('c1')`,
+	)
+	full, err := DumpString(dbName, WithStableOrder())
+	require.NoError(t, err)
+
+	// the dump was interrupted after the row of b with rowid 2, which was committed
+	interrupted := full[:strings.Index(full, `INSERT INTO "b" VALUES(3,'b3');`)] + "COMMIT;\n"
+	db := restoreDB(t, interrupted)
+	var after int64
+	require.NoError(t, db.QueryRow(`SELECT max(rowid) FROM b`).Scan(&after))
+	assert.Equal(t, int64(2), after)
+
+	got, err := DumpString(dbName, WithStableOrder(), WithResumeFrom("B", after), WithDropIfExists(true))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`DROP INDEX IF EXISTS "b_y";` + "\n" +
+		`DROP TABLE IF EXISTS "c";` + "\n" +
+		`INSERT INTO "b" VALUES(3,'b3');` + "\n" +
+		`INSERT INTO "b" VALUES(4,'b4');` + "\n" +
+		"CREATE TABLE c(z);\n" +
+		`INSERT INTO "c" VALUES('c1');` + "\n" +
+		"CREATE INDEX b_y ON b(y);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	// the restored database is the dumped one
+	_, err = db.Exec(got)
+	require.NoError(t, err)
+	restored, err := DumpDBString(db, WithStableOrder())
+	require.NoError(t, err)
+	assert.Equal(t, full, restored)
+
+	// a condition of the table still applies
+	got, err = DumpString(dbName, WithDataOnly(), WithResumeFrom("b", 1), WithWhere("b", "id <> 3"), WithTables("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "BEGIN TRANSACTION;\n"+
+		`INSERT INTO "b" VALUES(2,'b2');`+"\n"+
+		`INSERT INTO "b" VALUES(4,'b4');`+"\n"+
+		"COMMIT;\n", got)
+}
+
+func TestWithResumeFromStats(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(x TEXT)`,
+		`CREATE TABLE b(y TEXT)`,
+		`INSERT INTO a VALUES('a1')`,
+		`INSERT INTO b VALUES('b1'), ('b2'), ('b3'), ('b4'), ('b5')`,
+		`DELETE FROM b WHERE y = 'b2'`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	full, err := DumpDBString(db, WithStableOrder())
+	require.NoError(t, err)
+
+	// the dump fails writing the row of b with rowid 4, after that with rowid 3
+	w := &limitWriter{n: strings.Index(full, `INSERT INTO "b" VALUES('b4');`)}
+	stats, err := DumpDBStats(db, w, WithStableOrder())
+	require.Error(t, err)
+	assert.Equal(t, "b", stats.ResumeTable)
+	assert.Equal(t, int64(3), stats.ResumeAfterRowid)
+
+	// the restored rows have other rowids
+	restored := restoreDB(t, full[:w.written]+"COMMIT;\n")
+	var maxRowid int64
+	require.NoError(t, restored.QueryRow(`SELECT max(rowid) FROM b`).Scan(&maxRowid))
+	assert.Equal(t, int64(2), maxRowid)
+
+	got, err := DumpDBString(db, WithStableOrder(), WithResumeFrom(stats.ResumeTable, stats.ResumeAfterRowid))
+	require.NoError(t, err)
+	_, err = restored.Exec(got)
+	require.NoError(t, err)
+	dump, err := DumpDBString(restored, WithStableOrder())
+	require.NoError(t, err)
+	assert.Equal(t, full, dump)
+
+	// before any row of b, all of its rows are dumped
+	w = &limitWriter{n: strings.Index(full, `INSERT INTO "b" VALUES('b1');`)}
+	stats, err = DumpDBStats(db, w, WithStableOrder())
+	require.Error(t, err)
+	assert.Equal(t, "b", stats.ResumeTable)
+	assert.Equal(t, int64(math.MinInt64), stats.ResumeAfterRowid)
+
+	// the resumed dump failing before any row resumes from the same one
+	resumed, err := DumpDBString(db, WithStableOrder(), WithResumeFrom("b", 3))
+	require.NoError(t, err)
+	w = &limitWriter{n: strings.Index(resumed, `INSERT INTO "b" VALUES('b4');`)}
+	stats, err = DumpDBStats(db, w, WithStableOrder(), WithResumeFrom("b", 3))
+	require.Error(t, err)
+	assert.Equal(t, "b", stats.ResumeTable)
+	assert.Equal(t, int64(3), stats.ResumeAfterRowid)
+
+	// rows not dumped in rowid order can't be resumed from
+	for _, opts := range [][]Option{
+		nil,
+		{WithStableOrder(), WithOrderBy("b", "y DESC")},
+		{WithStableOrder(), WithConcurrency(2)},
+		{WithStableOrder(), WithBufferSize(64)},
+	} {
+		stats, err = DumpDBStats(db, &limitWriter{n: len(full) - 10}, opts...)
+		require.Error(t, err)
+		assert.Equal(t, "", stats.ResumeTable)
+	}
+}
+
+func TestWithResumeFromErrors(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE w(k TEXT PRIMARY KEY) WITHOUT ROWID`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	cases := []struct {
+		opts   []Option
+		expect string
+	}{
+		{[]Option{WithResumeFrom("w", 0)}, `can't resume from WITHOUT ROWID table "w"`},
+		{[]Option{WithResumeFrom("missing", 0)}, `table "missing" to resume from isn't dumped`},
+		{[]Option{WithResumeFrom("t", 0), WithExcludeTables("t")}, `table "t" to resume from isn't dumped`},
+		{[]Option{WithResumeFrom("T", 0), WithKeysetPagination("t", "id")}, `WithResumeFrom and WithKeysetPagination options can't be combined for table "T"`},
+		{[]Option{WithResumeFrom("t", 0), WithOrderBy("t", "id")}, `WithResumeFrom and WithOrderBy options can't be combined for table "t"`},
+	}
+	for _, c := range cases {
+		_, err := DumpDBString(db, c.opts...)
+		assert.EqualError(t, err, c.expect)
+	}
+}
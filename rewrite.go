@@ -0,0 +1,51 @@
+package sqlite3dump
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// createRegexp matches the start of a CREATE statement up to the object type keyword,
+// followed by an optional IF NOT EXISTS.
+var createRegexp = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:(?:TEMP|TEMPORARY)\s+)?(?:(?:UNIQUE|VIRTUAL)\s+)?(?:TABLE|INDEX|VIEW|TRIGGER)\b(\s+IF\s+NOT\s+EXISTS\b)?`)
+
+// createStatement returns the CREATE statement of the schema as it should be written,
+// or "" if the dialect leaves the object out.
+func (s3d *Dumper) createStatement(schema schema) string {
+	sql := trimTerminator(schema.SQL)
+	if schema.Temp {
+		sql = addTemp(sql)
+	}
+	if s3d.ifNotExists {
+		sql = addIfNotExists(sql)
+	}
+	if s3d.dialect != nil {
+		if sql = s3d.dialect.Create(schema.Type, schema.Name, sql); sql == "" {
+			return ""
+		}
+	}
+	if s3d.createRewriter != nil {
+		sql = trimTerminator(s3d.createRewriter(schema.Type, schema.Name, sql))
+	}
+	return sql
+}
+
+// trimTerminator removes the semicolon ending the statement, and the whitespace around it,
+// which some tools other than SQLite store in sqlite_master, so the dump doesn't end it
+// with a second one.
+func trimTerminator(sql string) string {
+	sql = strings.TrimRightFunc(sql, unicode.IsSpace)
+	sql = strings.TrimSuffix(sql, ";")
+	return strings.TrimRightFunc(sql, unicode.IsSpace)
+}
+
+// addIfNotExists inserts IF NOT EXISTS after the object type keyword of the CREATE
+// statement, unless it's already there.
+func addIfNotExists(sql string) string {
+	match := createRegexp.FindStringSubmatchIndex(sql)
+	if match == nil || match[2] >= 0 {
+		return sql
+	}
+	return sql[:match[1]] + " IF NOT EXISTS" + sql[match[1]:]
+}
@@ -0,0 +1,128 @@
+package sqlite3dump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddIfNotExists(t *testing.T) {
+	cases := map[string]string{
+		`CREATE TABLE t(a)`:                                       `CREATE TABLE IF NOT EXISTS t(a)`,
+		`create table "t"(a)`:                                     `create table IF NOT EXISTS "t"(a)`,
+		"CREATE\n  TEMP TABLE t(a)":                               "CREATE\n  TEMP TABLE IF NOT EXISTS t(a)",
+		`CREATE UNIQUE INDEX i ON t(a)`:                           `CREATE UNIQUE INDEX IF NOT EXISTS i ON t(a)`,
+		`CREATE VIEW v AS SELECT 1`:                               `CREATE VIEW IF NOT EXISTS v AS SELECT 1`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`: `CREATE TRIGGER IF NOT EXISTS tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+		`CREATE VIRTUAL TABLE f USING fts4(a)`:                    `CREATE VIRTUAL TABLE IF NOT EXISTS f USING fts4(a)`,
+		`CREATE TABLE if  not exists t(a)`:                        `CREATE TABLE if  not exists t(a)`,
+		`CREATE TABLE IF NOT EXISTS t(a)`:                         `CREATE TABLE IF NOT EXISTS t(a)`,
+		`CREATE TABLE tables(a)`:                                  `CREATE TABLE IF NOT EXISTS tables(a)`,
+	}
+
+	for sql, expect := range cases {
+		assert.Equal(t, expect, addIfNotExists(sql), sql)
+	}
+}
+
+func TestTrimTerminator(t *testing.T) {
+	cases := map[string]string{
+		`CREATE TABLE t(a)`:     `CREATE TABLE t(a)`,
+		`CREATE TABLE t(a);`:    `CREATE TABLE t(a)`,
+		"CREATE TABLE t(a) ;\n": `CREATE TABLE t(a)`,
+		`CREATE TABLE t(a);;`:   `CREATE TABLE t(a);`,
+		"CREATE VIEW v AS\n":    `CREATE VIEW v AS`,
+		`SELECT 1; END;`:        `SELECT 1; END`,
+		`CREATE TABLE "t;"(a)`:  `CREATE TABLE "t;"(a)`,
+	}
+	for sql, expect := range cases {
+		assert.Equal(t, expect, trimTerminator(sql), sql)
+	}
+}
+
+func TestStoredTerminator(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+		// like the statements stored by some other tools
+		`PRAGMA writable_schema=ON`,
+		`UPDATE sqlite_master SET sql = sql || ';' || char(10) WHERE name IN ('t', 't_a', 'v', 'tr')`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(a INTEGER);\n" +
+		`INSERT INTO "t" VALUES(1);` + "\n" +
+		"CREATE INDEX t_a ON t(a);\n" +
+		"CREATE VIEW v AS SELECT a FROM t;\n" +
+		"CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	restoreDB(t, got)
+}
+
+func TestWithIfNotExists(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+		`CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END`,
+	)
+
+	got, err := DumpString(dbName, WithIfNotExists())
+	require.NoError(t, err)
+
+	assert.Contains(t, got, "CREATE TABLE IF NOT EXISTS t(a INTEGER);\n")
+	assert.Contains(t, got, "CREATE INDEX IF NOT EXISTS t_a ON t(a);\n")
+	assert.Contains(t, got, "CREATE VIEW IF NOT EXISTS v AS SELECT a FROM t;\n")
+	assert.Contains(t, got, "CREATE TRIGGER IF NOT EXISTS tr AFTER INSERT ON t BEGIN SELECT 1; END;\n")
+
+	// loading the schema twice doesn't fail
+	restored := restoreDB(t, got)
+	schemaOnly, err := DumpString(dbName, WithIfNotExists(), WithoutData())
+	require.NoError(t, err)
+	_, err = restored.Exec(schemaOnly)
+	assert.NoError(t, err)
+}
+
+func TestWithCreateRewriter(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a INTEGER, b TEXT)`,
+		`INSERT INTO t VALUES(1, 'x')`,
+		`CREATE INDEX t_a ON t(a)`,
+		`CREATE VIEW v AS SELECT a FROM t`,
+	)
+
+	var called []string
+	strict := func(objType, name, sql string) string {
+		called = append(called, objType+" "+name)
+		if objType == "table" {
+			return sql + " STRICT;"
+		}
+		return sql
+	}
+	got, err := DumpString(dbName, WithCreateRewriter(strict), WithIfNotExists())
+	require.NoError(t, err)
+
+	// STRICT tables are newer than the SQLite of the tests, so the dump isn't restored
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE IF NOT EXISTS t(a INTEGER, b TEXT) STRICT;\n" +
+		`INSERT INTO "t" VALUES(1,'x');` + "\n" +
+		"CREATE INDEX IF NOT EXISTS t_a ON t(a);\n" +
+		"CREATE VIEW IF NOT EXISTS v AS SELECT a FROM t;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	assert.Equal(t, []string{"table t", "index t_a", "view v"}, called)
+
+	withNil, err := DumpString(dbName, WithCreateRewriter(nil))
+	require.NoError(t, err)
+	expect, err = DumpString(dbName)
+	require.NoError(t, err)
+	assert.Equal(t, expect, withNil)
+}
@@ -0,0 +1,85 @@
+package sqlite3dump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DumpRotating dumps a raw sql.DB into files of at most maxBytes bytes, named by the pattern
+// formatted with their number from 1, such as "dump.%03d.sql" for dump.001.sql, dump.002.sql
+// and so on, for uploads limited in size. A file ends with a whole statement, so the files
+// concatenated in order are the dump, and only a statement larger than maxBytes makes
+// a larger file, of its own. Returns the names of the files created, also on error.
+//
+// WithBufferSize is ignored, the files are buffered.
+func DumpRotating(db *sql.DB, pattern string, maxBytes int64, opts ...Option) ([]string, error) {
+	if strings.Contains(fmt.Sprintf(pattern, 1), "%!") {
+		return nil, fmt.Errorf("invalid file name pattern %q, it needs a verb for the number of the file", pattern)
+	}
+	if maxBytes < 1 {
+		return nil, fmt.Errorf("invalid file size %d", maxBytes)
+	}
+	s3d := New(opts...)
+	s3d.bufferSize = 0
+
+	rw := &rotatingWriter{pattern: pattern, max: maxBytes}
+	_, err := s3d.dumpDB(context.Background(), db, rw, rw)
+	if closeErr := rw.close(); err == nil {
+		err = closeErr
+	}
+	return rw.names, err
+}
+
+// rotatingWriter writes the statements written to it, one per Write, to the files of
+// DumpRotating, creating the next file when a statement doesn't fit in the current one.
+type rotatingWriter struct {
+	pattern string
+	max     int64
+	names   []string
+	file    *os.File
+	buf     *bufio.Writer
+	written int64
+}
+
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	if rw.file == nil || (rw.written > 0 && rw.written+int64(len(p)) > rw.max) {
+		if err := rw.next(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rw.buf.Write(p)
+	rw.written += int64(n)
+	return n, err
+}
+
+// next closes the current file and creates the next one.
+func (rw *rotatingWriter) next() error {
+	if err := rw.close(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf(rw.pattern, len(rw.names)+1)
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	rw.names = append(rw.names, name)
+	rw.file, rw.buf, rw.written = file, bufio.NewWriterSize(file, 64<<10), 0
+	return nil
+}
+
+// close writes out and closes the current file, if any.
+func (rw *rotatingWriter) close() error {
+	if rw.file == nil {
+		return nil
+	}
+	err := rw.buf.Flush()
+	if closeErr := rw.file.Close(); err == nil {
+		err = closeErr
+	}
+	rw.file = nil
+	return err
+}
@@ -0,0 +1,48 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpRotating(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, x TEXT)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50) INSERT INTO t SELECT i, 'row ' || i FROM n`,
+		`INSERT INTO t VALUES(100, '`+strings.Repeat("x", 500)+`')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	full, err := DumpDBString(db)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	names, err := DumpRotating(db, filepath.Join(dir, "dump.%03d.sql"), 400, WithBufferSize(16))
+	require.NoError(t, err)
+	require.True(t, len(names) >= 2, names)
+	assert.Equal(t, filepath.Join(dir, "dump.001.sql"), names[0])
+	assert.Equal(t, filepath.Join(dir, "dump.002.sql"), names[1])
+
+	var concatenated strings.Builder
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		require.NoError(t, err)
+		// every file ends with a whole statement, only the long one is larger
+		assert.True(t, strings.HasSuffix(string(b), ";\n"), name)
+		if !strings.Contains(string(b), strings.Repeat("x", 500)) {
+			assert.True(t, len(b) <= 400, name)
+		}
+		concatenated.Write(b)
+	}
+	assert.Equal(t, full, concatenated.String())
+
+	_, err = DumpRotating(db, filepath.Join(dir, "dump.sql"), 400)
+	assert.EqualError(t, err, `invalid file name pattern "`+filepath.Join(dir, "dump.sql")+`", it needs a verb for the number of the file`)
+}
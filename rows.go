@@ -0,0 +1,30 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// DumpRows calls fn with the column names and the values of every row of a table of a raw
+// sql.DB, for output formats of the caller's own or to insert them with a prepared statement.
+// The values are those the driver scans, int64, float64, string, []byte or nil, and the rows
+// are selected by the same options as those of DumpJSON, the table replacing the tables of
+// WithTables and WithTableGlob options. Returns an error if the table doesn't exist, and
+// the first error fn returns, which stops the dump.
+func DumpRows(db *sql.DB, table string, fn func(cols []string, vals []interface{}) error, opts ...Option) error {
+	s3d := New(opts...)
+	s3d.includeTables = map[string]string{strings.ToLower(table): table}
+	s3d.includeTableGlobs = nil
+	ctx := context.Background()
+	return s3d.export(ctx, db, func(db preparer, schema schema) error {
+		var columnNames []string
+		start := func(names []string) error {
+			columnNames = names
+			return nil
+		}
+		return s3d.exportRows(ctx, db, schema, start, func(values []interface{}) error {
+			return fn(columnNames, values)
+		})
+	})
+}
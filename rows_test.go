@@ -0,0 +1,48 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpRows(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, name TEXT, score REAL, data BLOB)`,
+		`CREATE TABLE other(x)`,
+		`INSERT INTO t VALUES(1, 'one', 1.5, x'00ff'), (2, NULL, NULL, NULL), (3, 'three', 3, NULL)`,
+		`INSERT INTO other VALUES(1)`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var columns []string
+	var rows [][]interface{}
+	collect := func(cols []string, vals []interface{}) error {
+		columns = cols
+		rows = append(rows, vals)
+		return nil
+	}
+	require.NoError(t, DumpRows(db, "T", collect, WithWhere("t", "id < 3"), WithTables("other")))
+	assert.Equal(t, []string{"id", "name", "score", "data"}, columns)
+	assert.Equal(t, [][]interface{}{
+		{int64(1), "one", 1.5, []byte{0, 0xff}},
+		{int64(2), nil, nil, nil},
+	}, rows)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = DumpRows(db, "t", func(cols []string, vals []interface{}) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+
+	err = DumpRows(db, "missing", collect)
+	assert.EqualError(t, err, `table "missing" doesn't exist`)
+}
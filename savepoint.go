@@ -0,0 +1,18 @@
+package sqlite3dump
+
+// beginSavepoint sets the savepoint named after the table, see WithPerTableSavepoints, as
+// the statement the dumpStart of the savepoints writes before the first statement of the
+// table, so a table that writes none has no savepoint.
+func (s3d *Dumper) beginSavepoint(savepoint *dumpStart, table string) {
+	savepoint.statements = []string{"SAVEPOINT " + s3d.dumpIdent(table) + ";\n"}
+	savepoint.started = false
+}
+
+// releaseSavepoint releases the savepoint of the table, if it was written.
+func (s3d *Dumper) releaseSavepoint(savepoint *dumpStart, table string) error {
+	if !savepoint.started {
+		savepoint.started = true
+		return nil
+	}
+	return writeStatement(savepoint.w, "RELEASE "+s3d.dumpIdent(table)+";\n")
+}
@@ -0,0 +1,76 @@
+package sqlite3dump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPerTableSavepoints(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(n INTEGER)`,
+		`CREATE TABLE "b c"(n INTEGER)`,
+		`CREATE TABLE empty(n INTEGER)`,
+		`CREATE INDEX a_n ON a(n)`,
+		`INSERT INTO a VALUES(1)`,
+		`INSERT INTO "b c" VALUES(2)`,
+	)
+
+	got, err := DumpString(dbName, WithPerTableSavepoints())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`SAVEPOINT "a";` + "\n" +
+		"CREATE TABLE a(n INTEGER);\n" +
+		`INSERT INTO "a" VALUES(1);` + "\n" +
+		`RELEASE "a";` + "\n" +
+		`SAVEPOINT "b c";` + "\n" +
+		`CREATE TABLE "b c"(n INTEGER);` + "\n" +
+		`INSERT INTO "b c" VALUES(2);` + "\n" +
+		`RELEASE "b c";` + "\n" +
+		`SAVEPOINT "empty";` + "\n" +
+		"CREATE TABLE empty(n INTEGER);\n" +
+		`RELEASE "empty";` + "\n" +
+		"CREATE INDEX a_n ON a(n);\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	db := restoreDB(t, got)
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT n FROM "b c"`).Scan(&n))
+	assert.Equal(t, 2, n)
+
+	// a table rolled back to its savepoint leaves the others restored
+	db = restoreDB(t, "BEGIN TRANSACTION;\n"+
+		`SAVEPOINT "a";`+"\nCREATE TABLE a(n INTEGER);\n"+`ROLLBACK TO "a";`+"\n"+`RELEASE "a";`+"\n"+
+		`SAVEPOINT "b c";`+"\n"+`CREATE TABLE "b c"(n INTEGER);`+"\n"+`RELEASE "b c";`+"\n"+
+		"COMMIT;\n")
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables))
+	assert.Equal(t, 1, tables)
+
+	// only in the transaction of the dump
+	got, err = DumpString(dbName, WithPerTableSavepoints(), WithTransaction(false), WithTables("a"))
+	require.NoError(t, err)
+	assert.NotContains(t, got, "SAVEPOINT")
+
+	// a table left out of the data has no savepoint
+	got, err = DumpString(dbName, WithPerTableSavepoints(), WithDataOnly(), WithSkipEmptyTables())
+	require.NoError(t, err)
+	expect = "BEGIN TRANSACTION;\n" +
+		`SAVEPOINT "a";` + "\n" +
+		`INSERT INTO "a" VALUES(1);` + "\n" +
+		`RELEASE "a";` + "\n" +
+		`SAVEPOINT "b c";` + "\n" +
+		`INSERT INTO "b c" VALUES(2);` + "\n" +
+		`RELEASE "b c";` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	emptyDB := createDB(t, `CREATE TABLE a(n INTEGER)`, `CREATE TABLE b(n INTEGER)`)
+	got, err = DumpString(emptyDB, WithPerTableSavepoints(), WithDataOnly(), WithSkipEmptyTables())
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	_, err = DumpString(dbName, WithPerTableSavepoints(), WithCheckpointEvery(10))
+	assert.EqualError(t, err, "WithPerTableSavepoints and WithCheckpointEvery options can't be combined")
+}
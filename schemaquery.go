@@ -0,0 +1,133 @@
+package sqlite3dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// customSchemas returns the tables and the other objects the query of WithSchemaQuery
+// selects, in its order.
+func (s3d *Dumper) customSchemas(ctx context.Context, db preparer) (tableSchemas, otherSchemas []schema, err error) {
+	var schemas []schema
+	for attempt := 1; ; attempt++ {
+		schemas, err = s3d.queryCustomSchemas(ctx, db)
+		if !s3d.retryBusy(ctx, err, attempt) {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	tableSchemas, otherSchemas = []schema{}, []schema{}
+	for _, s := range schemas {
+		switch s.Type {
+		case "table":
+			tableSchemas = append(tableSchemas, s)
+		case "index", "trigger", "view":
+			otherSchemas = append(otherSchemas, s)
+		default:
+			return nil, nil, fmt.Errorf("the schema query returned %q of unknown type %q", s.Name, s.Type)
+		}
+	}
+	return
+}
+
+func (s3d *Dumper) queryCustomSchemas(ctx context.Context, db preparer) (schemas []schema, err error) {
+	stmt, err := db.PrepareContext(ctx, s3d.schemaQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema query: %w", err)
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	if len(columns) != 3 && len(columns) != 4 {
+		return nil, fmt.Errorf("the schema query returns %d columns rather than name, type and sql, or name, type, tbl_name and sql", len(columns))
+	}
+
+	schemas = []schema{}
+	for rows.Next() {
+		var (
+			s                  schema
+			tableName, sqlText sql.NullString
+		)
+		if len(columns) == 4 {
+			err = rows.Scan(&s.Name, &s.Type, &tableName, &sqlText)
+		} else {
+			err = rows.Scan(&s.Name, &s.Type, &sqlText)
+		}
+		if err != nil {
+			return
+		}
+		// like sqlite_master, the automatic indexes have no statement, they aren't dumped
+		if !sqlText.Valid {
+			continue
+		}
+		s.TableName, s.SQL = tableName.String, sqlText.String
+		schemas = append(schemas, s)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if len(columns) == 3 {
+		if err = s3d.addTableNames(ctx, db, schemas); err != nil {
+			return
+		}
+	}
+	for _, s := range schemas {
+		if err = checkName(s.Type, s.Name); err != nil {
+			return
+		}
+		if err = checkName("table", s.TableName); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// addTableNames sets the tables of the indexes and the triggers that a schema query without
+// a tbl_name column returned, as sqlite_master has them.
+func (s3d *Dumper) addTableNames(ctx context.Context, db preparer, schemas []schema) error {
+	stmt, err := db.PrepareContext(ctx, `SELECT "name", "tbl_name" FROM `+s3d.schemaPrefix()+`"sqlite_master"`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tableNames := map[string]string{}
+	for rows.Next() {
+		var name, tableName string
+		if err = rows.Scan(&name, &tableName); err != nil {
+			return err
+		}
+		tableNames[strings.ToLower(name)] = tableName
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i, s := range schemas {
+		if tableName, ok := tableNames[strings.ToLower(s.Name)]; ok {
+			schemas[i].TableName = tableName
+		} else {
+			schemas[i].TableName = s.Name
+		}
+	}
+	return nil
+}
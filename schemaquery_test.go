@@ -0,0 +1,54 @@
+package sqlite3dump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSchemaQuery(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE b(x TEXT UNIQUE)`,
+		`CREATE TABLE a(y INTEGER)`,
+		`CREATE TABLE skipped(z INTEGER)`,
+		`CREATE INDEX a_y ON a(y)`,
+		`CREATE INDEX skipped_z ON skipped(z)`,
+		`CREATE VIEW v AS SELECT x FROM b`,
+		`INSERT INTO a VALUES(1)`,
+		`INSERT INTO b VALUES('b')`,
+	)
+
+	// by creation order rather than by name, leaving out a table
+	for name, q := range map[string]string{
+		"without tbl_name": `SELECT name, type, sql FROM sqlite_master WHERE name <> 'skipped' ORDER BY rowid`,
+		"with tbl_name":    `SELECT name, type, tbl_name, sql FROM sqlite_master WHERE name <> 'skipped' ORDER BY rowid`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, WithSchemaQuery(q))
+			require.NoError(t, err)
+			expect := "BEGIN TRANSACTION;\n" +
+				"CREATE TABLE b(x TEXT UNIQUE);\n" +
+				`INSERT INTO "b" VALUES('b');` + "\n" +
+				"CREATE TABLE a(y INTEGER);\n" +
+				`INSERT INTO "a" VALUES(1);` + "\n" +
+				"CREATE INDEX a_y ON a(y);\n" +
+				"CREATE INDEX skipped_z ON skipped(z);\n" +
+				"CREATE VIEW v AS SELECT x FROM b;\n" +
+				"COMMIT;\n"
+			assert.Equal(t, expect, got)
+
+			// the indexes go with their tables
+			got, err = DumpString(dbName, WithSchemaQuery(q), WithExcludeTables("a"))
+			require.NoError(t, err)
+			assert.NotContains(t, got, "a_y")
+		})
+	}
+
+	_, err := DumpString(dbName, WithSchemaQuery(`SELECT name, sql FROM sqlite_master`))
+	assert.EqualError(t, err, "the schema query returns 2 columns rather than name, type and sql, or name, type, tbl_name and sql")
+	_, err = DumpString(dbName, WithSchemaQuery(`SELECT name, 'column', sql FROM sqlite_master`))
+	assert.EqualError(t, err, `the schema query returned "b" of unknown type "column"`)
+	_, err = DumpString(dbName, WithSchemaQuery(`SELECT missing FROM sqlite_master`))
+	assert.EqualError(t, err, "invalid schema query: no such column: missing")
+}
@@ -0,0 +1,92 @@
+package sqlite3dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// withoutSequenceTable returns the table schemas without sqlite_sequence, and its schema if it was there.
+func withoutSequenceTable(tableSchemas []schema) (kept []schema, sequenceTable schema, found bool) {
+	kept = make([]schema, 0, len(tableSchemas))
+	for _, schema := range tableSchemas {
+		if schema.Name == "sqlite_sequence" {
+			sequenceTable, found = schema, true
+			continue
+		}
+		kept = append(kept, schema)
+	}
+	return
+}
+
+// writeSequenceTableCreate writes the CREATE statement of sqlite_sequence with IF NOT EXISTS,
+// see WithExplicitSequenceTable. The name is reserved to SQLite, only a writable schema
+// lets a statement create the table.
+func (s3d *Dumper) writeSequenceTableCreate(w io.Writer, schema schema) error {
+	statement := s3d.createStatement(schema)
+	if statement == "" {
+		return nil
+	}
+	for _, statement := range []string{"PRAGMA writable_schema=ON;\n", addIfNotExists(statement) + ";\n", "PRAGMA writable_schema=OFF;\n"} {
+		if err := writeStatement(w, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSequenceTable resets sqlite_sequence and inserts its rows, like the other tables or
+// with WithSequences. It comes after the rows of all the other tables, so the inserts into
+// the AUTOINCREMENT tables, which update sqlite_sequence, can't change the dumped values.
+func (s3d *Dumper) writeSequenceTable(ctx context.Context, dataOut, rowsOut io.Writer, db preparer, schema schema) (rowsDumped int64, err error) {
+	if s3d.sequences {
+		if s3d.schemaOnly {
+			return
+		}
+		return s3d.writeSequences(ctx, rowsOut, db)
+	}
+
+	if err = writeStatement(dataOut, "DELETE FROM "+s3d.targetIdent("sqlite_sequence")+";\n"); err != nil {
+		return
+	}
+	if s3d.schemaOnly {
+		return
+	}
+	return s3d.writeInsStmtsForTableRows(ctx, rowsOut, db, schema, nil)
+}
+
+// writeSequences resets sqlite_sequence and inserts its current rows,
+// which has to come after the rows of the AUTOINCREMENT tables, whose inserts update it.
+func (s3d *Dumper) writeSequences(ctx context.Context, w io.Writer, db preparer) (rowsDumped int64, err error) {
+	if err = writeStatement(w, "DELETE FROM "+s3d.targetIdent("sqlite_sequence")+";\n"); err != nil {
+		return
+	}
+
+	stmt, err := db.PrepareContext(ctx, `
+		SELECT quote("name"), quote("seq") FROM `+s3d.schemaPrefix()+`"sqlite_sequence" ORDER BY "name"
+	`)
+	if err != nil {
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, seq string
+		if err = rows.Scan(&name, &seq); err != nil {
+			return
+		}
+		statement := fmt.Sprintf("INSERT INTO %s(%s,%s) VALUES(%s,%s);\n",
+			s3d.targetIdent("sqlite_sequence"), s3d.dumpIdent("name"), s3d.dumpIdent("seq"), name, seq)
+		if err = writeStatement(w, statement); err != nil {
+			return
+		}
+		rowsDumped++
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,138 @@
+package sqlite3dump
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSequences(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`CREATE TABLE z(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`INSERT INTO a(name) VALUES('one'), ('two'), ('three')`,
+		`INSERT INTO z(name) VALUES('one'), ('two')`,
+		`DELETE FROM a WHERE id = 3`,
+		`DELETE FROM z WHERE id = 2`,
+	)
+
+	got, err := DumpString(dbName, WithSequences())
+	require.NoError(t, err)
+
+	// the sequences come after the rows of both tables
+	tail := `INSERT INTO "z" VALUES(1,'one');` + "\n" +
+		`DELETE FROM "sqlite_sequence";` + "\n" +
+		`INSERT INTO "sqlite_sequence"("name","seq") VALUES('a',3);` + "\n" +
+		`INSERT INTO "sqlite_sequence"("name","seq") VALUES('z',2);` + "\n" +
+		"COMMIT;\n"
+	assert.True(t, strings.HasSuffix(got, tail), got)
+	assert.Equal(t, 1, strings.Count(got, `DELETE FROM "sqlite_sequence";`))
+
+	db := restoreDB(t, got)
+	_, err = db.Exec(`INSERT INTO a(name) VALUES('four')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO z(name) VALUES('three')`)
+	require.NoError(t, err)
+
+	var aID, zID int
+	require.NoError(t, db.QueryRow(`SELECT max(id) FROM a`).Scan(&aID))
+	require.NoError(t, db.QueryRow(`SELECT max(id) FROM z`).Scan(&zID))
+	assert.Equal(t, 4, aID, "the id of the deleted row is not reused")
+	assert.Equal(t, 3, zID, "the id of the deleted row is not reused")
+}
+
+func TestWithSequencesWithoutData(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT)`,
+		`INSERT INTO a DEFAULT VALUES`,
+	)
+
+	got, err := DumpString(dbName, WithSequences(), WithoutData())
+	require.NoError(t, err)
+	assert.NotContains(t, got, "sqlite_sequence")
+}
+
+func TestSequenceTableAfterTableData(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`CREATE TABLE z(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`CREATE INDEX z_name ON z(name)`,
+		`INSERT INTO a(name) VALUES('one'), ('two')`,
+		`INSERT INTO z(name) VALUES('one')`,
+		`DELETE FROM a WHERE id = 2`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	// "z" sorts after "sqlite_sequence" but its rows still come before it
+	del := strings.Index(got, `DELETE FROM "sqlite_sequence";`)
+	require.NotEqual(t, -1, del, got)
+	assert.True(t, strings.LastIndex(got, `INSERT INTO "a" `) < del, got)
+	assert.True(t, strings.LastIndex(got, `INSERT INTO "z" `) < del, got)
+	assert.True(t, del < strings.Index(got, "CREATE INDEX z_name"), got)
+
+	db := restoreDB(t, got)
+	_, err = db.Exec(`INSERT INTO a(name) VALUES('three')`)
+	require.NoError(t, err)
+	var id int
+	require.NoError(t, db.QueryRow(`SELECT max(id) FROM a`).Scan(&id))
+	assert.Equal(t, 3, id)
+}
+
+func TestWithExplicitSequenceTable(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)`,
+		`INSERT INTO a(name) VALUES('one'), ('two')`,
+		`DELETE FROM a WHERE id = 2`,
+	)
+
+	got, err := DumpString(dbName, WithExplicitSequenceTable())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"PRAGMA writable_schema=ON;\n" +
+		"CREATE TABLE IF NOT EXISTS sqlite_sequence(name,seq);\n" +
+		"PRAGMA writable_schema=OFF;\n" +
+		"CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);\n" +
+		`INSERT INTO "a" VALUES(1,'one');` + "\n" +
+		`DELETE FROM "sqlite_sequence";` + "\n" +
+		`INSERT INTO "sqlite_sequence" VALUES('a',2);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	// the table is created first, and restoring it again keeps it
+	db := restoreDB(t, got)
+	again, err := DumpString(dbName, WithExplicitSequenceTable(), WithIfNotExists(), WithInsertVerb("INSERT OR IGNORE"))
+	require.NoError(t, err)
+	_, err = db.Exec(again)
+	require.NoError(t, err)
+	var names string
+	require.NoError(t, db.QueryRow(`SELECT group_concat(name) FROM (SELECT name FROM sqlite_master ORDER BY rootpage)`).Scan(&names))
+	assert.Equal(t, "sqlite_sequence,a", names)
+	_, err = db.Exec(`INSERT INTO a(name) VALUES('three')`)
+	require.NoError(t, err)
+	var id int
+	require.NoError(t, db.QueryRow(`SELECT max(id) FROM a`).Scan(&id))
+	assert.Equal(t, 3, id)
+
+	// there's nothing to create without an AUTOINCREMENT table
+	dbName = createDB(t,
+		`CREATE TABLE b(id INTEGER PRIMARY KEY, name TEXT)`,
+		`INSERT INTO b(name) VALUES('one')`,
+	)
+	got, err = DumpString(dbName, WithExplicitSequenceTable())
+	require.NoError(t, err)
+	expect = "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE b(id INTEGER PRIMARY KEY, name TEXT);\n" +
+		`INSERT INTO "b" VALUES(1,'one');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	// the data of a dump goes into the existing tables
+	dbName = createDB(t, `CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT)`)
+	got, err = DumpString(dbName, WithExplicitSequenceTable(), WithDataOnly())
+	require.NoError(t, err)
+	assert.NotContains(t, got, "CREATE TABLE")
+}
@@ -0,0 +1,21 @@
+//go:build go1.21
+// +build go1.21
+
+package sqlite3dump
+
+import "log/slog"
+
+// WithLogger option logs the progress of the dump for its operators: the start of each table
+// at the debug level, its end with the number of rows dumped and the end of the dump at
+// the info level, and the diagnostics of WithWarn at the warn level. Unlike WithProgress, it's
+// not meant for a user interface. A nil logger disables it, which is the default.
+func WithLogger(l *slog.Logger) Option {
+	return func(dumper *Dumper) {
+		// a nil *slog.Logger would make a logger interface that isn't nil
+		if l == nil {
+			dumper.logger = nil
+			return
+		}
+		dumper.logger = l
+	}
+}
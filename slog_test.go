@@ -0,0 +1,72 @@
+//go:build go1.21
+// +build go1.21
+
+package sqlite3dump
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordHandler keeps the records logged, as their level, message and attributes.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Level.String() + " " + r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		b.WriteString(" " + a.String())
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, b.String())
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+func TestWithLogger(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE a(id INTEGER PRIMARY KEY AUTOINCREMENT, s STRING)`,
+		`CREATE TABLE b(x)`,
+		`CREATE INDEX b_x ON b(x)`,
+		`INSERT INTO a(s) VALUES('1'), ('2')`,
+	)
+
+	h := &recordHandler{}
+	expect, err := DumpString(dbName)
+	require.NoError(t, err)
+	got, err := DumpString(dbName, WithLogger(slog.New(h)))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+
+	assert.Equal(t, []string{
+		"DEBUG dumping table table=a",
+		`WARN column "s" of table "a" has type "STRING", which has NUMERIC affinity: text that looks like a number is stored as a number`,
+		"INFO dumped table table=a rows=2",
+		"DEBUG dumping table table=b",
+		"INFO dumped table table=b rows=0",
+		"DEBUG dumping table table=sqlite_sequence",
+		"INFO dumped table table=sqlite_sequence rows=1",
+		"INFO dumped database tables=2 rows=3 indexes=1 triggers=0 views=0",
+	}, h.records)
+
+	// a nil logger disables it
+	got, err = DumpString(dbName, WithLogger(nil))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+}
@@ -0,0 +1,70 @@
+package sqlite3dump
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// tempSchemas returns the tables and the other objects of the temp schema, see WithTemp.
+// The tables SQLite uses internally are left out, like temp.sqlite_sequence.
+func (s3d *Dumper) tempSchemas(ctx context.Context, db preparer) (tableSchemas, otherSchemas []schema, err error) {
+	tableSchemas, err = s3d.getSchemas(ctx, db, `
+        SELECT "name", "type", "tbl_name", "sql"
+        FROM "sqlite_temp_master"
+            WHERE "sql" NOT NULL AND
+            "type" == 'table' AND
+            "name" NOT LIKE 'sqlite\_%' ESCAPE '\'
+            ORDER BY "name"
+		`)
+	if err != nil {
+		return
+	}
+	otherSchemas, err = s3d.getSchemas(ctx, db, `
+		SELECT "name", "type", "tbl_name", "sql"
+        FROM "sqlite_temp_master"
+            WHERE "sql" NOT NULL AND
+            "type" IN ('index', 'trigger', 'view')
+		`)
+	if err != nil {
+		return
+	}
+	for i := range tableSchemas {
+		tableSchemas[i].Temp = true
+	}
+	for i := range otherSchemas {
+		otherSchemas[i].Temp = true
+	}
+	return
+}
+
+// tableKey identifies a table by its schema and its name in lowercase, as WithTemp can dump
+// a temp table named like a table of the main schema.
+type tableKey struct {
+	temp bool
+	name string
+}
+
+// keyOf returns the key of the table of the schema.
+func keyOf(schema schema) tableKey {
+	return tableKey{temp: schema.Temp, name: strings.ToLower(schema.Name)}
+}
+
+// tablePrefix returns the quoted schema name the table is in, followed by a dot,
+// for qualifying the queries of the table.
+func (s3d *Dumper) tablePrefix(schema schema) string {
+	if schema.Temp {
+		return `"temp".`
+	}
+	return s3d.schemaPrefix()
+}
+
+// createTempRegexp matches the start of a CREATE TABLE, VIEW or TRIGGER statement that isn't
+// already TEMP. The indexes of temp tables are created in the temp schema without it.
+var createTempRegexp = regexp.MustCompile(`(?is)^(\s*CREATE)(\s+(?:TABLE|VIEW|TRIGGER)\b)`)
+
+// addTemp inserts TEMP after CREATE, as SQLite leaves it out of the statements
+// stored in sqlite_temp_master.
+func addTemp(sql string) string {
+	return createTempRegexp.ReplaceAllString(sql, "${1} TEMP${2}")
+}
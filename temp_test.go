@@ -0,0 +1,106 @@
+package sqlite3dump
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTemp(t *testing.T) {
+	db, err := sql.Open("sqlite3", createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, b TEXT)`,
+		`INSERT INTO t VALUES(1, 'main')`,
+	))
+	require.NoError(t, err)
+	defer db.Close()
+	// the temp objects only exist on the connection that created them
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		`CREATE TEMP TABLE session(id INTEGER PRIMARY KEY AUTOINCREMENT, v TEXT)`,
+		`INSERT INTO session(v) VALUES('a'), ('b')`,
+		`CREATE INDEX session_v ON session(v)`,
+		`CREATE TEMP VIEW session_view AS SELECT v FROM session`,
+		`CREATE TEMP TRIGGER t_insert AFTER INSERT ON t BEGIN SELECT 1; END`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	got, err := DumpDBString(db)
+	require.NoError(t, err)
+	assert.NotContains(t, got, "session")
+
+	got, err = DumpDBString(db, WithTemp())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		"CREATE TABLE t(id INTEGER PRIMARY KEY, b TEXT);\n" +
+		`INSERT INTO "t" VALUES(1,'main');` + "\n" +
+		"CREATE TEMP TABLE session(id INTEGER PRIMARY KEY AUTOINCREMENT, v TEXT);\n" +
+		`INSERT INTO "session" VALUES(1,'a');` + "\n" +
+		`INSERT INTO "session" VALUES(2,'b');` + "\n" +
+		"CREATE INDEX session_v ON session(v);\n" +
+		"CREATE TEMP VIEW session_view AS SELECT v FROM session;\n" +
+		"CREATE TEMP TRIGGER t_insert AFTER INSERT ON t BEGIN SELECT 1; END;\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	restored, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer restored.Close()
+	restored.SetMaxOpenConns(1)
+	_, err = restored.Exec(got)
+	require.NoError(t, err)
+	var tempTables int
+	require.NoError(t, restored.QueryRow(`SELECT count(*) FROM sqlite_temp_master WHERE name IN ('session', 'session_v', 'session_view', 't_insert')`).Scan(&tempTables))
+	assert.Equal(t, 4, tempTables)
+}
+
+func TestWithTempSameName(t *testing.T) {
+	db, err := sql.Open("sqlite3", createDB(t,
+		`CREATE TABLE t(a INTEGER)`,
+		`CREATE TABLE e(a INTEGER)`,
+		`INSERT INTO t VALUES(1)`,
+	))
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		`CREATE TEMP TABLE t(a INTEGER)`,
+		`CREATE TEMP TABLE e(a INTEGER)`,
+		`INSERT INTO temp.t VALUES(2)`,
+		`INSERT INTO temp.e VALUES(3)`,
+	} {
+		_, err = db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	// the empty main table e doesn't leave out the temp one
+	got, err := DumpDBString(db, WithTemp(), WithDataOnly(), WithSkipEmptyTables())
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t" VALUES(1);` + "\n" +
+		`INSERT INTO "e" VALUES(3);` + "\n" +
+		`INSERT INTO "t" VALUES(2);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+
+	_, err = DumpDBString(db, WithTemp(), WithConcurrency(2))
+	assert.EqualError(t, err, "WithTemp and WithConcurrency options can't be combined")
+
+	// the workers keep the rows of the tables of the same name apart
+	s3d := New(WithConcurrency(2))
+	tables := []schema{{Name: "t", Type: "table"}, {Name: "t", Type: "table", Temp: true}}
+	var b bytes.Buffer
+	writeRows, wait := s3d.dumpRowsConcurrently(context.Background(), db, tables, &b)
+	defer wait()
+	for _, table := range tables {
+		rows, err := writeRows(table)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), rows)
+	}
+	assert.Equal(t, `INSERT INTO "t" VALUES(1);`+"\n"+`INSERT INTO "t" VALUES(2);`+"\n", b.String())
+}
@@ -1,5 +1,5 @@
 BEGIN TRANSACTION;
-DROP TABLE IF EXISTS Cars;
+DROP TABLE IF EXISTS "Cars";
 CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);
 INSERT INTO "Cars" VALUES(1,'Audi',52642);
 INSERT INTO "Cars" VALUES(2,'Mercedes',57127);
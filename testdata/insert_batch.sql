@@ -0,0 +1,6 @@
+BEGIN TRANSACTION;
+CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);
+INSERT INTO "Cars" VALUES(1,'Audi',52642),(2,'Mercedes',57127),(3,'Skoda',9000);
+INSERT INTO "Cars" VALUES(4,'Volvo',29000),(5,'Bentley',350000),(6,'Citroen',21000);
+INSERT INTO "Cars" VALUES(7,'Hummer',41400),(8,'Volkswagen',21600);
+COMMIT;
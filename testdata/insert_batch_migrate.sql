@@ -0,0 +1,4 @@
+BEGIN TRANSACTION;
+INSERT INTO "Cars"("Id","Name","Price") VALUES(1,'Audi',52642),(2,'Mercedes',57127),(3,'Skoda',9000),(4,'Volvo',29000);
+INSERT INTO "Cars"("Id","Name","Price") VALUES(5,'Bentley',350000),(6,'Citroen',21000),(7,'Hummer',41400),(8,'Volkswagen',21600);
+COMMIT;
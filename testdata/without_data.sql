@@ -0,0 +1,3 @@
+BEGIN TRANSACTION;
+CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);
+COMMIT;
@@ -0,0 +1,4 @@
+BEGIN TRANSACTION;
+DROP TABLE IF EXISTS "Cars";
+CREATE TABLE Cars(Id INTEGER PRIMARY KEY, Name TEXT, Price INTEGER);
+COMMIT;
@@ -0,0 +1,162 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// QuoteValue returns the SQL literal of a value scanned from SQLite, the same as the quote()
+// function of SQLite returns for it: NULL for nil, the integer or the real, with a decimal
+// point, of an int64 or a float64, the single-quoted text of a string, up to its first NUL
+// character, with its quotes doubled, and X'<hex>' for a []byte. The digits of a real may
+// differ from those of quote() but read back as the same float64, and the infinite reals,
+// which quote() writes as Inf and -Inf that don't read back, are written as 9.0e+999 and
+// -9.0e+999, see quoteReal.
+// The other integer and floating point types are formatted like int64 and float64, booleans
+// as 1 and 0 like SQLite stores them, and the other values as the text fmt.Sprint formats.
+func QuoteValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return quoteReal(v)
+	case string:
+		// like quote(), the text ends at a NUL character, which SQL text can't hold
+		if i := strings.IndexByte(v, 0); i >= 0 {
+			v = v[:i]
+		}
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case []byte:
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+
+	// the other Go values a row filter can return
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return quoteReal(rv.Float())
+	default:
+		return QuoteValue(fmt.Sprint(v))
+	}
+}
+
+// quotedInfinities rewrites the Inf and -Inf that quote() writes for the infinite reals, which
+// don't read back, in the values of a row it formatted, like quoteReal. Inf can only be found
+// outside the string literals as such a real, and rarely at all.
+func quotedInfinities(row string) string {
+	if !strings.Contains(row, "Inf") {
+		return row
+	}
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(row); i++ {
+		// a doubled quote leaves and enters the literal
+		if row[i] == '\'' {
+			inString = !inString
+		} else if !inString && strings.HasPrefix(row[i:], "Inf") {
+			b.WriteString("9.0e+999")
+			i += len("Inf") - 1
+			continue
+		}
+		b.WriteByte(row[i])
+	}
+	return b.String()
+}
+
+// columnValue returns the expression selecting the value of the column in the row query.
+func (s3d *Dumper) columnValue(table, column string) string {
+	if s3d.timeColumns[strings.ToLower(table)][strings.ToLower(column)] {
+		return fmt.Sprintf(`datetime(%s,'unixepoch')`, quoteIdent(column))
+	}
+	return quoteIdent(column)
+}
+
+// valueSelects returns the columns selected by the row query of the scan-based path. The unary
+// plus makes them expressions, which the driver returns as stored, rather than converting
+// those of the columns declared as DATE, DATETIME or TIMESTAMP to time.Time.
+func (s3d *Dumper) valueSelects(table string, columnNames []string) []string {
+	selects := make([]string, len(columnNames))
+	for i, c := range columnNames {
+		selects[i] = "+" + s3d.columnValue(table, c)
+	}
+	return selects
+}
+
+// scanRow scans the values of a row of the scan-based path and formats them as the
+// parenthesized values of an INSERT statement, with the dialect if set, for the
+// declared types of the columns. The row is passed through the filter,
+// if not nil, which can leave it out of the dump.
+func (s3d *Dumper) scanRow(rows *sql.Rows, table string, columnNames, columnTypes []string, filter rowFilter, extra ...interface{}) (row string, include bool, err error) {
+	values, include, err := s3d.scanRowValues(rows, table, columnNames, filter, extra...)
+	if err != nil || !include {
+		return
+	}
+
+	literals := make([]string, len(values))
+	for i, v := range values {
+		if v == nil && s3d.nullAs != nil {
+			literals[i] = *s3d.nullAs
+			continue
+		}
+		if s3d.dialect != nil {
+			literals[i] = s3d.dialect.Value(v, columnTypes[i])
+			continue
+		}
+		literals[i] = QuoteValue(v)
+	}
+	return "(" + strings.Join(literals, ",") + ")", true, nil
+}
+
+// scanRowValues scans the values of a row of the scan-based path and passes them through
+// the filter, if not nil, which can leave the row out, then through the column transforms
+// of the table. The columns selected after the values are scanned into extra.
+func (s3d *Dumper) scanRowValues(rows *sql.Rows, table string, columnNames []string, filter rowFilter, extra ...interface{}) (values []interface{}, include bool, err error) {
+	values = make([]interface{}, len(columnNames))
+	pointers := make([]interface{}, len(columnNames), len(columnNames)+len(extra))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err = rows.Scan(append(pointers, extra...)...); err != nil {
+		return
+	}
+
+	if filter != nil {
+		if include, values = filter(columnNames, values); !include {
+			return
+		}
+		if len(values) != len(columnNames) {
+			err = fmt.Errorf("the row filter of table %q returned %d values for %d columns", table, len(values), len(columnNames))
+			return
+		}
+	}
+
+	if transforms := s3d.columnTransforms[strings.ToLower(table)]; transforms != nil {
+		for i, name := range columnNames {
+			if fn := transforms[strings.ToLower(name)]; fn != nil {
+				values[i] = fn(values[i])
+			}
+		}
+	}
+	return values, true, nil
+}
+
+// scanValues reports whether the rows of the table are scanned and formatted in Go,
+// rather than formatted by SQLite with quote() in the row query.
+func (s3d *Dumper) scanValues(table string) bool {
+	table = strings.ToLower(table)
+	return s3d.nullAs != nil || s3d.dialect != nil || s3d.rowFilters[table] != nil || s3d.columnTransforms[table] != nil
+}
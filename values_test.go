@@ -0,0 +1,257 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithNullAs(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER, name TEXT, note TEXT)`,
+		`INSERT INTO t VALUES(1, 'a', NULL), (2, NULL, 'it''s'), (NULL, NULL, NULL)`,
+	)
+
+	got, err := DumpString(dbName, WithNullAs("''"), WithDataOnly())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t" VALUES(1,'a','');` + "\n" +
+		`INSERT INTO "t" VALUES(2,'','it''s');` + "\n" +
+		`INSERT INTO "t" VALUES('','','');` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestScanValuesMatchQuote(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(v, d DATETIME, ts TIMESTAMP)`,
+		`INSERT INTO t VALUES
+			(NULL, '2020-01-02 03:04:05', 1600000000),
+			(0, 'not a date', NULL),
+			(-9223372036854775808, 5, 2.5),
+			(9223372036854775807, x'', x'00ff10'),
+			(1.0, 0.1, -0.0),
+			(1e20, 1e-5, 123456789012345678.0),
+			(3.141592653589793, 1.0/3, 2e300),
+			('', 'it''s "quoted"', 'multi
+line'),
+			('日本', char(0), 'tab	')`,
+	)
+
+	expect, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	got, err := DumpString(dbName, WithNullAs("NULL"))
+	require.NoError(t, err)
+	// the last digits of the reals with 20 decimals may differ, they restore the same values
+	restored, err := DumpDBString(restoreDB(t, got))
+	require.NoError(t, err)
+	assert.Equal(t, expect, restored)
+}
+
+func TestWithRowFilter(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE users(id INTEGER, email TEXT, deleted INTEGER)`,
+		`CREATE TABLE other(email TEXT)`,
+		`INSERT INTO users VALUES(1, 'alice@example.com', 0), (2, 'bob@example.com', 1), (3, NULL, 0)`,
+		`INSERT INTO other VALUES('carol@example.com')`,
+	)
+
+	var gotColumns []string
+	redact := func(cols []string, vals []interface{}) (bool, []interface{}) {
+		gotColumns = cols
+		if vals[2] == int64(1) {
+			return false, nil
+		}
+		if email, ok := vals[1].(string); ok {
+			vals[1] = "redacted" + email[strings.Index(email, "@"):]
+		}
+		return true, vals
+	}
+
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+	// the rows left out aren't counted
+	stats, err := DumpDBStats(db, &strings.Builder{}, WithRowFilter("Users", redact))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Rows)
+
+	got, err := DumpString(dbName, WithRowFilter("Users", redact), WithDataOnly())
+	require.NoError(t, err)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "other" VALUES('carol@example.com');` + "\n" +
+		`INSERT INTO "users" VALUES(1,'redacted@example.com',0);` + "\n" +
+		`INSERT INTO "users" VALUES(3,NULL,0);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+	assert.Equal(t, []string{"id", "email", "deleted"}, gotColumns)
+}
+
+func TestWithRowFilterGoValues(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a, b, c, d, e)`,
+		`INSERT INTO t VALUES(1, 2, 3, 4, 5)`,
+	)
+
+	got, err := DumpString(dbName, WithDataOnly(), WithRowFilter("t", func(cols []string, vals []interface{}) (bool, []interface{}) {
+		return true, []interface{}{int32(-7), uint8(8), float32(0.5), true, []string{"x"}}
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, got, `INSERT INTO "t" VALUES(-7,8,0.5,1,'[x]');`)
+
+	_, err = DumpString(dbName, WithRowFilter("t", func(cols []string, vals []interface{}) (bool, []interface{}) {
+		return true, vals[:1]
+	}))
+	assert.EqualError(t, err, `the row filter of table "t" returned 1 values for 5 columns`)
+}
+
+func TestWithTimeColumn(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE events(id INTEGER, created INTEGER, updated INTEGER, other INTEGER)`,
+		`CREATE TABLE other(created INTEGER)`,
+		`INSERT INTO events VALUES(1, 0, 1600000000, 1600000000), (2, NULL, 1600000000.5, 7)`,
+		`INSERT INTO other VALUES(1600000000)`,
+	)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "events" VALUES(1,'1970-01-01 00:00:00','2020-09-13 12:26:40',1600000000);` + "\n" +
+		`INSERT INTO "events" VALUES(2,NULL,'2020-09-13 12:26:40',7);` + "\n" +
+		`INSERT INTO "other" VALUES(1600000000);` + "\n" +
+		"COMMIT;\n"
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"scanned": {WithNullAs("NULL")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := DumpString(dbName, append(opts, WithDataOnly(), WithTimeColumn("Events", "created"), WithTimeColumn("events", "UPDATED"))...)
+			require.NoError(t, err)
+			assert.Equal(t, expect, got)
+		})
+	}
+}
+
+func TestWithColumnTransform(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE users(id INTEGER, name TEXT, email TEXT)`,
+		`INSERT INTO users VALUES(1, 'alice', 'alice@example.com'), (2, NULL, 'bob@example.com')`,
+	)
+
+	upper := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return strings.ToUpper(s)
+		}
+		return v
+	}
+	hide := func(v interface{}) interface{} { return nil }
+
+	got, err := DumpString(dbName, WithDataOnly(), WithColumnTransform("Users", "NAME", upper), WithColumnTransform("users", "email", hide))
+	require.NoError(t, err)
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "users" VALUES(1,'ALICE',NULL);` + "\n" +
+		`INSERT INTO "users" VALUES(2,NULL,NULL);` + "\n" +
+		"COMMIT;\n"
+	assert.Equal(t, expect, got)
+}
+
+func TestQuoteValue(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	values := []interface{}{
+		nil,
+		int64(0), int64(1), int64(-1), int64(math.MaxInt64), int64(math.MinInt64),
+		0.0, math.Copysign(0, -1), 1.0, -1.5, 0.1, 1.0 / 3, 100.0, 1e15, 1e16, 1e20, 1e-5, 1e-7,
+		123456789012345678.0, 3.141592653589793, 2e300, -2e-300, math.MaxFloat64, math.SmallestNonzeroFloat64,
+		"", "a", "it's", `"quoted"`, "''", "multi\nline", "tab\t", "日本語", "emoji 😀", "nul\x00after", "\x01\x7f",
+		[]byte{}, []byte{0}, []byte{0x00, 0xff, 0x10}, []byte("text"),
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		values = append(values,
+			r.Int63()-r.Int63(),
+			r.NormFloat64()*math.Pow(10, float64(r.Intn(40)-20)),
+			math.Float64frombits(r.Uint64()&^(0x7ff<<52)|uint64(r.Intn(0x7fe)+1)<<52),
+		)
+	}
+
+	stmt, err := db.Prepare(`SELECT quote(?)`)
+	require.NoError(t, err)
+	defer stmt.Close()
+	for _, v := range values {
+		if f, ok := v.(float64); ok {
+			// the digits of a real may differ from those of quote(), it reads back the same
+			got, err := strconv.ParseFloat(QuoteValue(v), 64)
+			require.NoError(t, err, v)
+			assert.Equal(t, f, got, "%#v", v)
+			continue
+		}
+		var expect string
+		require.NoError(t, stmt.QueryRow(v).Scan(&expect), v)
+		assert.Equal(t, expect, QuoteValue(v), "%#v", v)
+	}
+
+	// SQLite reads the usual reals back the same too
+	for _, f := range []float64{0.1, 1.0 / 3, 3.141592653589793, 123456789012345678.0, 2e300, 1e-7, math.MaxFloat64} {
+		var got float64
+		require.NoError(t, db.QueryRow(`SELECT `+QuoteValue(f)).Scan(&got), f)
+		assert.Equal(t, f, got, "%#v", f)
+	}
+
+	// the reals have a decimal point, with 20 decimals if 15 digits don't read back
+	for v, expect := range map[float64]string{
+		0: "0.0", 1: "1.0", -1.5: "-1.5", 0.1: "0.1", 1e20: "1.0e+20", 1e-7: "1.0e-07",
+		100: "100.0", 1.0 / 3: "3.33333333333333314830e-01", 3.141592653589793: "3.14159265358979311600e+00",
+	} {
+		assert.Equal(t, expect, QuoteValue(v), "%#v", v)
+	}
+
+	// the Go values of the row filters
+	for v, expect := range map[interface{}]string{
+		int32(-7): "-7", uint8(8): "8", uint64(math.MaxUint64): "18446744073709551615",
+		float32(0.5): "0.5", true: "1", false: "0", struct{ A int }{1}: "'{1}'",
+	} {
+		assert.Equal(t, expect, QuoteValue(v), "%#v", v)
+	}
+}
+
+func TestInfiniteReals(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY, v REAL, note TEXT)`,
+		`INSERT INTO t VALUES(1, 9e999, 'Inf'), (2, -9e999, 'it''s Inf'), (3, 1.5, NULL), (4, 0.0/0, '-Inf')`,
+	)
+
+	expect := "BEGIN TRANSACTION;\n" +
+		`INSERT INTO "t" VALUES(1,9.0e+999,'Inf');` + "\n" +
+		`INSERT INTO "t" VALUES(2,-9.0e+999,'it''s Inf');` + "\n" +
+		`INSERT INTO "t" VALUES(3,1.5,NULL);` + "\n" +
+		`INSERT INTO "t" VALUES(4,NULL,'-Inf');` + "\n" +
+		"COMMIT;\n"
+	got, err := DumpString(dbName, WithDataOnly())
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+	got, err = DumpString(dbName, WithDataOnly(), WithNullAs("NULL"))
+	require.NoError(t, err)
+	assert.Equal(t, expect, got)
+
+	dump, err := DumpString(dbName)
+	require.NoError(t, err)
+	db := restoreDB(t, dump)
+	var pos, neg float64
+	require.NoError(t, db.QueryRow(`SELECT (SELECT v FROM t WHERE id = 1), (SELECT v FROM t WHERE id = 2)`).Scan(&pos, &neg))
+	assert.True(t, math.IsInf(pos, 1))
+	assert.True(t, math.IsInf(neg, -1))
+
+	assert.Equal(t, "9.0e+999", QuoteValue(math.Inf(1)))
+	assert.Equal(t, "-9.0e+999", QuoteValue(math.Inf(-1)))
+	assert.Equal(t, "NULL", QuoteValue(math.NaN()))
+}
@@ -0,0 +1,39 @@
+package sqlite3dump
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shadowSuffixes are the suffixes of the shadow tables each module creates automatically
+// along with its virtual table.
+var shadowSuffixes = map[string][]string{
+	"fts3":      {"_content", "_segments", "_segdir", "_docsize", "_stat"},
+	"fts4":      {"_content", "_segments", "_segdir", "_docsize", "_stat"},
+	"fts5":      {"_content", "_data", "_idx", "_docsize", "_config"},
+	"rtree":     {"_node", "_parent", "_rowid"},
+	"rtree_i32": {"_node", "_parent", "_rowid"},
+	"geopoly":   {"_node", "_parent", "_rowid"},
+}
+
+// virtualTableRegexp matches a CREATE VIRTUAL TABLE statement, capturing the module name.
+var virtualTableRegexp = regexp.MustCompile(`(?is)^CREATE\s+VIRTUAL\s+TABLE\s.*?\sUSING\s+(\w+)`)
+
+// shadowTables maps the lowercased names of the shadow tables of the virtual tables to the
+// names of their virtual tables.
+// Shadow tables mustn't be dumped, recreating the virtual table creates them again.
+// Only the suffixes of each virtual table's own module are considered, so a user table
+// such as article_content is kept unless article is an FTS table.
+func shadowTables(tableSchemas []schema) map[string]string {
+	shadows := map[string]string{}
+	for _, s := range tableSchemas {
+		match := virtualTableRegexp.FindStringSubmatch(s.SQL)
+		if match == nil {
+			continue
+		}
+		for _, suffix := range shadowSuffixes[strings.ToLower(match[1])] {
+			shadows[strings.ToLower(s.Name+suffix)] = s.Name
+		}
+	}
+	return shadows
+}
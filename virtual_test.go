@@ -0,0 +1,127 @@
+package sqlite3dump
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualTables(t *testing.T) {
+	cases := map[string]struct {
+		module string
+		insert string
+		query  string
+	}{
+		"FTS4": {
+			module: "fts4(title, body)",
+			insert: `INSERT INTO docs VALUES('first', 'hello world'), ('second', 'it''s a test')`,
+			query:  `SELECT title FROM docs WHERE docs MATCH 'test'`,
+		},
+		"FTS5": {
+			module: "fts5(title, body)",
+			insert: `INSERT INTO docs VALUES('first', 'hello world'), ('second', 'it''s a test')`,
+			query:  `SELECT title FROM docs WHERE docs MATCH 'test'`,
+		},
+		"R-Tree": {
+			module: "rtree(id, minx, maxx)",
+			insert: `INSERT INTO docs VALUES(1, 0, 10), (2, 20, 30)`,
+			query:  `SELECT id FROM docs WHERE minx >= 15`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dbName := filepath.Join(t.TempDir(), "virtual.db")
+			db, err := sql.Open("sqlite3", dbName)
+			require.NoError(t, err)
+			defer db.Close()
+			// FTS5 is only available when building with -tags sqlite_fts5
+			if _, err = db.Exec("CREATE VIRTUAL TABLE docs USING " + c.module); err != nil && strings.Contains(err.Error(), "no such module") {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			_, err = db.Exec(c.insert)
+			require.NoError(t, err)
+
+			var expect string
+			require.NoError(t, db.QueryRow(c.query).Scan(&expect))
+
+			dump, err := DumpDBString(db)
+			require.NoError(t, err)
+			assert.Contains(t, dump, "CREATE VIRTUAL TABLE docs USING "+c.module+";\n")
+			assert.NotContains(t, dump, `CREATE TABLE "docs_`)
+			assert.NotContains(t, dump, `CREATE TABLE 'docs_`)
+
+			restored := restoreDB(t, dump)
+			var got string
+			require.NoError(t, restored.QueryRow(c.query).Scan(&got))
+			assert.Equal(t, expect, got)
+		})
+	}
+}
+
+func TestUserTablesWithShadowSuffixes(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE my_content(body TEXT)`,
+		`CREATE TABLE page_data(body TEXT)`,
+		`CREATE VIRTUAL TABLE docs USING fts4(body)`,
+		`CREATE TABLE docs_config(body TEXT)`,
+		`INSERT INTO my_content VALUES('kept')`,
+		`INSERT INTO page_data VALUES('kept')`,
+		`INSERT INTO docs_config VALUES('kept')`,
+	)
+
+	got, err := DumpString(dbName)
+	require.NoError(t, err)
+
+	assert.Contains(t, got, "CREATE TABLE my_content(body TEXT);\n"+`INSERT INTO "my_content" VALUES('kept');`)
+	assert.Contains(t, got, "CREATE TABLE page_data(body TEXT);\n"+`INSERT INTO "page_data" VALUES('kept');`)
+	assert.Contains(t, got, "CREATE TABLE docs_config(body TEXT);\n"+`INSERT INTO "docs_config" VALUES('kept');`)
+	assert.NotContains(t, got, "docs_content")
+	assert.NotContains(t, got, "docs_segdir")
+}
+
+func TestShadowTables(t *testing.T) {
+	shadows := shadowTables([]schema{
+		{Name: "Docs", SQL: `CREATE VIRTUAL TABLE "Docs" USING FTS5(body)`},
+		{Name: "geo", SQL: "create virtual table if not exists geo\nusing rtree(id, minx, maxx)"},
+		{Name: "custom", SQL: `CREATE VIRTUAL TABLE custom USING unknown(x)`},
+		{Name: "plain", SQL: `CREATE TABLE plain(x)`},
+	})
+
+	expect := map[string]string{
+		"docs_content": "Docs", "docs_data": "Docs", "docs_idx": "Docs", "docs_docsize": "Docs", "docs_config": "Docs",
+		"geo_node": "geo", "geo_parent": "geo", "geo_rowid": "geo",
+	}
+	assert.Equal(t, expect, shadows)
+}
+
+func TestSkippedTablesStats(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(id INTEGER PRIMARY KEY AUTOINCREMENT)`,
+		`CREATE VIRTUAL TABLE Docs USING fts4(body)`,
+		`INSERT INTO t VALUES(NULL)`,
+		`INSERT INTO Docs VALUES('hello')`,
+	)
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	defer db.Close()
+
+	stats, err := DumpDBStats(db, &strings.Builder{})
+	require.NoError(t, err)
+	reason := `shadow table of virtual table "Docs", which creates it`
+	expect := []SkippedTable{
+		{Name: "Docs_content", Reason: reason},
+		{Name: "Docs_docsize", Reason: reason},
+		{Name: "Docs_segdir", Reason: reason},
+		{Name: "Docs_segments", Reason: reason},
+		{Name: "Docs_stat", Reason: reason},
+	}
+	assert.Equal(t, expect, stats.SkippedTables)
+	// the virtual table and t are dumped, sqlite_sequence isn't skipped
+	assert.Equal(t, 2, stats.Tables)
+}
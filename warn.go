@@ -0,0 +1,79 @@
+package sqlite3dump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownTypes are the declared types whose affinity is the one their name suggests,
+// those of https://www.sqlite.org/datatype3.html#affinity_name_examples.
+var knownTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "TINYINT": true, "SMALLINT": true, "MEDIUMINT": true,
+	"BIGINT": true, "UNSIGNED BIG INT": true, "INT2": true, "INT8": true,
+	"NUMERIC": true, "DECIMAL": true, "BOOLEAN": true, "DATE": true, "DATETIME": true,
+}
+
+// warnColumnTypes calls the WithWarn hook for the columns whose declared type has an affinity
+// the name doesn't suggest, such as STRING, which has NUMERIC affinity, or FLOATING POINT,
+// which has INTEGER affinity because it contains INT. Their values may not be what the user
+// expects, the dump restores them as they are stored.
+func (s3d *Dumper) warnColumnTypes(table string, columns []column) {
+	if !s3d.warns() {
+		return
+	}
+	for _, c := range columns {
+		name := strings.ToUpper(c.Type)
+		if i := strings.IndexByte(name, '('); i >= 0 {
+			name = name[:i]
+		}
+		name = strings.Join(strings.Fields(name), " ")
+		if knownTypes[name] {
+			continue
+		}
+		switch affinity(name) {
+		case "INTEGER":
+			s3d.warnf("column %q of table %q has type %q, which has INTEGER affinity because it contains INT: reals without a fractional part are stored as integers", c.Name, table, c.Type)
+		case "NUMERIC":
+			s3d.warnf("column %q of table %q has type %q, which has NUMERIC affinity: text that looks like a number is stored as a number", c.Name, table, c.Type)
+		}
+	}
+}
+
+// affinity returns the type affinity of the declared type, determined by the rules of
+// https://www.sqlite.org/datatype3.html#determination_of_column_affinity.
+func affinity(declaredType string) string {
+	t := strings.ToUpper(declaredType)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "INTEGER"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "TEXT"
+	case strings.Contains(t, "BLOB"), strings.TrimSpace(t) == "":
+		return "BLOB"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "REAL"
+	default:
+		return "NUMERIC"
+	}
+}
+
+// warns reports whether the diagnostics of WithWarn are reported, to the hook or to
+// the logger of WithLogger.
+func (s3d *Dumper) warns() bool {
+	return s3d.warn != nil || s3d.logger != nil
+}
+
+// warnf calls the WithWarn hook with the formatted message, never concurrently, and logs it
+// with the logger of WithLogger.
+func (s3d *Dumper) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if s3d.logger != nil {
+		s3d.logger.Warn(msg)
+	}
+	if s3d.warn == nil {
+		return
+	}
+	s3d.warnMu.Lock()
+	defer s3d.warnMu.Unlock()
+	s3d.warn(msg)
+}
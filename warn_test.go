@@ -0,0 +1,49 @@
+package sqlite3dump
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWarn(t *testing.T) {
+	dbName := createDB(t,
+		`CREATE TABLE t(a STRING, b FLOATING POINT, c VARCHAR(10), d DECIMAL(10, 2), e JSON, f, g DATETIME, h unsigned  big int)`,
+		`INSERT INTO t(a) VALUES('0123')`,
+	)
+
+	var warnings []string
+	_, err := DumpString(dbName, WithWarn(func(msg string) {
+		warnings = append(warnings, msg)
+	}))
+	require.NoError(t, err)
+	expect := []string{
+		`column "a" of table "t" has type "STRING", which has NUMERIC affinity: text that looks like a number is stored as a number`,
+		`column "b" of table "t" has type "FLOATING POINT", which has INTEGER affinity because it contains INT: reals without a fractional part are stored as integers`,
+		`column "e" of table "t" has type "JSON", which has NUMERIC affinity: text that looks like a number is stored as a number`,
+	}
+	assert.Equal(t, expect, warnings)
+
+	_, err = DumpString(dbName, WithWarn(nil))
+	require.NoError(t, err)
+}
+
+func TestAffinity(t *testing.T) {
+	cases := map[string]string{
+		"INTEGER":          "INTEGER",
+		"unsigned big int": "INTEGER",
+		"FLOATING POINT":   "INTEGER",
+		"VARCHAR(255)":     "TEXT",
+		"clob":             "TEXT",
+		"BLOB":             "BLOB",
+		"":                 "BLOB",
+		"DOUBLE PRECISION": "REAL",
+		"FLOAT":            "REAL",
+		"DECIMAL(10,5)":    "NUMERIC",
+		"STRING":           "NUMERIC",
+	}
+	for declaredType, expect := range cases {
+		assert.Equal(t, expect, affinity(declaredType), declaredType)
+	}
+}